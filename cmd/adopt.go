@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagAdoptSymlink bool
+	flagAdoptDryRun  bool
+	flagAdoptName    string
+)
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <path>...",
+	Short: "Move or symlink existing directories into the workspace",
+	Long: `Bring existing directories into the workspace under the naming scheme. The date is
+taken from the first git commit, or from the oldest file when the directory is not
+a repository.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAdopt,
+}
+
+func init() {
+	adoptCmd.Flags().BoolVar(&flagAdoptSymlink, "symlink", false, "Leave directories in place and link them into the workspace")
+	adoptCmd.Flags().BoolVar(&flagAdoptDryRun, "dry-run", false, "Print the plan without changing anything")
+	adoptCmd.Flags().StringVar(&flagAdoptName, "name", "", "Name for the adopted try (single path only)")
+	rootCmd.AddCommand(adoptCmd)
+}
+
+func runAdopt(cmd *cobra.Command, args []string) error {
+	if flagAdoptName != "" && len(args) > 1 {
+		return fmt.Errorf("--name can only be used with a single path")
+	}
+
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+	root, err := cfg.DefaultRoot()
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		src, err := filepath.Abs(config.ExpandHome(arg))
+		if err != nil {
+			return err
+		}
+		if err := adoptDir(cfg, root, src); err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+	}
+	return nil
+}
+
+func adoptDir(cfg *config.Config, root workspace.Root, src string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory")
+	}
+	for _, r := range cfg.Roots() {
+		if workspace.Within(r.Path, src) || workspace.Within(src, r.Path) {
+			return fmt.Errorf("overlaps workspace %s", r.Path)
+		}
+	}
+
+	date, source := adoptionDate(src)
+
+	name := flagAdoptName
+	if name == "" {
+		name = filepath.Base(src)
+	}
+	slug := workspace.Slugify(name, cfg.Naming().MaxSlugLength)
+	if slug == "" {
+		return fmt.Errorf("name %q has no usable characters", name)
+	}
+	rel, err := cfg.Naming().TryNameAt(slug, date)
+	if err != nil {
+		return err
+	}
+
+	verb := "move"
+	if flagAdoptSymlink {
+		verb = "link"
+	}
+	if flagAdoptDryRun {
+		fmt.Printf("%s %s → %s (dated from %s)\n", verb, src, filepath.Join(root.Path, rel), source)
+		return nil
+	}
+
+	// Take a free name and put the directory there without giving the
+	// name up in between
+	var dest string
+	if flagAdoptSymlink {
+		dest, err = workspace.ReserveLink(src, filepath.Join(root.Path, rel))
+	} else {
+		dest, err = workspace.Reserve(filepath.Join(root.Path, rel))
+		if err == nil {
+			err = workspace.MoveInto(src, dest)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !flagAdoptSymlink && git.IsRepo(dest) {
+		if err := git.RepairWorktrees(dest); err != nil {
+			return err
+		}
+	}
+
+	rel, _ = filepath.Rel(root.Path, dest)
+	err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+		entry := m.Entry(rel)
+		entry.Origin = workspace.OriginAdopt
+		entry.AdoptedFrom = src
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(dest)
+	return nil
+}
+
+// adoptionDate picks the date an adopted directory is filed under.
+func adoptionDate(path string) (time.Time, string) {
+	if git.IsRepo(path) {
+		if t, err := git.FirstCommitTime(path); err == nil {
+			return t, "first commit"
+		}
+	}
+	if t, err := workspace.OldestModTime(path); err == nil && !t.IsZero() {
+		return t, "oldest file"
+	}
+	info, _ := os.Stat(path)
+	return info.ModTime(), "directory mtime"
+}
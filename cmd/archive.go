@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagArchiveFormat string
+	flagArchiveSlim   bool
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive <try>...",
+	Short: "Pack tries into compressed tarballs and remove them",
+	Long: `Pack tries into .tar.gz or .tar.zst archives (archive.dir, the root's .gotry/archive
+by default) and remove the originals. Archived tries stay in the selector and are
+restored when selected. --slim leaves out dependency and build folders such as
+node_modules and target.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runArchive,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <try>",
+	Short: "Unpack an archived try back into the workspace",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runRestore,
+}
+
+func init() {
+	archiveCmd.Flags().StringVar(&flagArchiveFormat, "format", "", "Archive format: tar.gz or tar.zst (default archive.format)")
+	archiveCmd.Flags().BoolVar(&flagArchiveSlim, "slim", false, "Leave out dependency and build folders")
+	rootCmd.AddCommand(archiveCmd, restoreCmd)
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	format := cfg.Archive.Format
+	if flagArchiveFormat != "" {
+		format = flagArchiveFormat
+	}
+	var exclude []string
+	if flagArchiveSlim || cfg.Archive.Slim {
+		exclude = cfg.Archive.Exclude
+	}
+
+	for _, ref := range args {
+		dir, err := findTry(cfg, ref)
+		if err != nil {
+			return err
+		}
+		// Committed files inside excluded folders are kept
+		var tracked []string
+		if len(exclude) > 0 && !dir.Archived() && git.IsRepo(dir.Path) {
+			if tracked, err = git.TrackedFiles(dir.Path); err != nil {
+				return fmt.Errorf("%s: listing tracked files: %w", dir.Name, err)
+			}
+		}
+		dest, err := workspace.ArchiveTry(dir, cfg.ArchiveDir(dir.RootPath), format, exclude, tracked)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir.Name, err)
+		}
+		fmt.Println(dest)
+	}
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if err := workspace.RestoreTry(dir); err != nil {
+		return err
+	}
+
+	fmt.Println(dir.Path)
+	return nil
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagCleanAll    bool
+	flagCleanDryRun bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean [--all | <try>...]",
+	Short: "Delete regenerable build artifacts to reclaim disk space",
+	Long: `Delete dependency and build folders such as node_modules, target and .venv from
+tries. Folders are recognised per ecosystem (see [clean.artifacts] to change the
+catalogue), and files tracked by git are always kept. With clean.after_days set,
+tries unused for that many days are cleaned when the selector opens.`,
+	RunE: runClean,
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&flagCleanAll, "all", false, "Clean every try")
+	cleanCmd.Flags().BoolVar(&flagCleanDryRun, "dry-run", false, "Show reclaimable space without deleting")
+	rootCmd.AddCommand(cleanCmd)
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	var dirs []workspace.Directory
+	switch {
+	case flagCleanAll && len(args) > 0:
+		return fmt.Errorf("pass either --all or tries, not both")
+	case flagCleanAll:
+		dirs, err = workspace.ListRoots(cfg.Roots(), cfg.Naming())
+		if err != nil {
+			return err
+		}
+	case len(args) > 0:
+		for _, ref := range args {
+			dir, err := findTry(cfg, ref)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, dir)
+		}
+	default:
+		return fmt.Errorf("specify tries to clean or --all")
+	}
+
+	rules := cfg.ArtifactRules()
+	var total int64
+	for _, dir := range dirs {
+		if dir.Graduated() || dir.Archived() {
+			continue
+		}
+		artifacts, err := findArtifacts(dir, rules)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir.Name, err)
+		}
+		if len(artifacts) == 0 {
+			continue
+		}
+
+		var size int64
+		names := make([]string, len(artifacts))
+		for i, a := range artifacts {
+			size += a.Size
+			names[i] = strings.TrimPrefix(a.Path, dir.Path+string(os.PathSeparator))
+		}
+		total += size
+		fmt.Printf("%-45s %8s  %s\n", dir.Name, workspace.HumanSize(size), strings.Join(names, ", "))
+
+		if !flagCleanDryRun {
+			if err := cleanTry(dir, artifacts); err != nil {
+				return fmt.Errorf("%s: %w", dir.Name, err)
+			}
+		}
+	}
+
+	verb := "Reclaimed"
+	if flagCleanDryRun {
+		verb = "Would reclaim"
+	}
+	fmt.Printf("%s %s\n", verb, workspace.HumanSize(total))
+	return nil
+}
+
+// findArtifacts lists the artifacts of a try, protecting git-tracked files.
+// A repository whose files cannot be listed is not cleaned at all.
+func findArtifacts(dir workspace.Directory, rules map[string]workspace.ArtifactRule) ([]workspace.Artifact, error) {
+	var tracked []string
+	if git.IsRepo(dir.Path) {
+		var err error
+		if tracked, err = git.TrackedFiles(dir.Path); err != nil {
+			return nil, fmt.Errorf("listing tracked files: %w", err)
+		}
+	}
+	return workspace.FindArtifacts(dir.Path, rules, tracked)
+}
+
+// cleanTry removes artifacts and records when the try was cleaned.
+func cleanTry(dir workspace.Directory, artifacts []workspace.Artifact) error {
+	for _, a := range artifacts {
+		if err := a.Remove(); err != nil {
+			return err
+		}
+	}
+	return workspace.UpdateMeta(dir.RootPath, func(m *workspace.Meta) error {
+		m.Entry(dir.Name).CleanedAt = time.Now()
+		return nil
+	})
+}
+
+// autoCleanInterval is how often autoClean looks at a root.
+const autoCleanInterval = 24 * time.Hour
+
+// autoClean purges artifacts from unpinned tries that have gone unused for
+// clean.after_days, skipping those already cleaned since their last use.
+// Each root is looked at once per autoCleanInterval, so most launches go
+// straight to the selector.
+func autoClean(cfg *config.Config) {
+	if cfg.Clean.AfterDays <= 0 {
+		return
+	}
+	var roots []workspace.Root
+	for _, r := range cfg.Roots() {
+		if workspace.CleanDue(r.Path, autoCleanInterval) {
+			roots = append(roots, r)
+		}
+	}
+	if len(roots) == 0 {
+		return
+	}
+	dirs, err := workspace.ListRoots(roots, cfg.Naming())
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -cfg.Clean.AfterDays)
+	rules := cfg.ArtifactRules()
+	var total int64
+	count := 0
+	for _, dir := range dirs {
+		if dir.Graduated() || dir.Archived() || dir.Meta.Pinned {
+			continue
+		}
+		if dir.LastUsed().After(cutoff) || dir.Meta.CleanedAt.After(dir.LastUsed()) {
+			continue
+		}
+		artifacts, err := findArtifacts(dir, rules)
+		if err != nil {
+			continue
+		}
+		var size int64
+		for _, a := range artifacts {
+			size += a.Size
+		}
+		if cleanTry(dir, artifacts) == nil && size > 0 {
+			total += size
+			count++
+		}
+	}
+
+	if count > 0 {
+		fmt.Fprintf(os.Stderr, "gotry: cleaned %s of build artifacts from %d idle tries\n", workspace.HumanSize(total), count)
+	}
+}
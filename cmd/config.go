@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/raiden076/gotry/internal/config"
 	"github.com/spf13/cobra"
@@ -20,20 +21,45 @@ func init() {
 }
 
 func runConfig(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := config.Load(flagProfile)
 	if err != nil {
 		return err
 	}
 
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".config", "gotry", "config.toml")
+	configPath := config.Path()
 
 	fmt.Println("Configuration")
 	fmt.Println("─────────────")
 	fmt.Printf("Config file:    %s\n", configPath)
-	fmt.Printf("Workspace:      %s\n", cfg.Workspace.Path)
+	if cfg.Profile != "" {
+		fmt.Printf("Profile:        %s\n", cfg.Profile)
+	}
+	if names := cfg.ProfileNames(); len(names) > 0 {
+		fmt.Printf("Profiles:       %s\n", strings.Join(names, ", "))
+	}
+	if roots := cfg.Roots(); len(roots) > 1 {
+		for i, r := range roots {
+			label := "Workspaces:"
+			if i > 0 {
+				label = ""
+			}
+			fmt.Printf("%-15s %s (%s)\n", label, r.Path, r.Name)
+		}
+		def, _ := cfg.DefaultRoot()
+		clone, _ := cfg.CloneRoot()
+		fmt.Printf("Create in:      %s\n", def.Name)
+		fmt.Printf("Clone into:     %s\n", clone.Name)
+	} else {
+		fmt.Printf("Workspace:      %s\n", roots[0].Path)
+	}
 	fmt.Printf("Auto git init:  %t\n", cfg.Git.AutoInit)
 	fmt.Printf("Initial commit: %t\n", cfg.Git.InitialCommit)
+	if cfg.Templates.Default != "" {
+		fmt.Printf("Template:       %s\n", cfg.TemplatePath(cfg.Templates.Default))
+	}
+	if len(cfg.Hooks.PostCreate) > 0 {
+		fmt.Printf("Post-create:    %s\n", strings.Join(cfg.Hooks.PostCreate, "; "))
+	}
 
 	// Check if config file exists
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var flagDuQuery string
+
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Show how much disk space each try uses",
+	Long: `Show the disk usage of each try, largest first, with the workspace total. Sizes
+are cached per directory and only re-measured where something changed.`,
+	Args: cobra.NoArgs,
+	RunE: runDu,
+}
+
+func init() {
+	duCmd.Flags().StringVarP(&flagDuQuery, "query", "q", "", "Filter expression")
+	rootCmd.AddCommand(duCmd)
+}
+
+func runDu(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := queryTries(cfg, flagDuQuery)
+	if err != nil {
+		return err
+	}
+	sizes := workspace.ScanSizes(dirs)
+	workspace.SortBySize(dirs, sizes)
+
+	var total int64
+	for _, d := range dirs {
+		size, ok := sizes[d.Path]
+		if !ok {
+			continue
+		}
+		total += size
+		fmt.Printf("%8s  %-45s %5s  %s\n", workspace.HumanSize(size), d.Name, workspace.RelativeTime(d.LastUsed()), d.Root)
+	}
+	fmt.Printf("%8s  total\n", workspace.HumanSize(total))
+
+	if flagDuQuery == "" {
+		checkQuota(cfg, dirs, sizes)
+	}
+	return nil
+}
+
+// checkQuota prints a warning on stderr naming the largest and the least
+// recently used tries when their total exceeds workspace.quota.
+func checkQuota(cfg *config.Config, dirs []workspace.Directory, sizes map[string]int64) {
+	if cfg.Workspace.Quota == "" {
+		return
+	}
+	quota, err := query.ParseSize(cfg.Workspace.Quota)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotry: workspace.quota: %v\n", err)
+		return
+	}
+
+	var total int64
+	var present []workspace.Directory
+	for _, d := range dirs {
+		if size, ok := sizes[d.Path]; ok {
+			total += size
+			present = append(present, d)
+		}
+	}
+	if total <= quota {
+		return
+	}
+
+	describe := func(list []workspace.Directory) string {
+		var parts []string
+		for i, d := range list {
+			if i == 3 {
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s (%s, %s)", d.Name, workspace.HumanSize(sizes[d.Path]), workspace.RelativeTime(d.LastUsed())))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	largest := append([]workspace.Directory(nil), present...)
+	workspace.SortBySize(largest, sizes)
+	stale := append([]workspace.Directory(nil), present...)
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastUsed().Before(stale[j].LastUsed())
+	})
+
+	fmt.Fprintf(os.Stderr, "gotry: workspace uses %s, over its %s quota\n", workspace.HumanSize(total), workspace.HumanSize(quota))
+	fmt.Fprintf(os.Stderr, "  largest: %s\n", describe(largest))
+	fmt.Fprintf(os.Stderr, "  least recently used: %s\n", describe(stale))
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+// restoreEnv holds the values the variables had before entering an
+// isolated try, null for unset ones.
+const restoreEnv = "_GOTRY_RESTORE"
+
+var envCmd = &cobra.Command{
+	Use:   "env <bash|zsh|fish|powershell> [dir]",
+	Short: "Print commands switching the environment to an isolated try",
+	Long: `Print shell commands that point HOME and the XDG directories into the isolated
+try at dir, or restore them when dir is empty. The shell integration runs this
+whenever the current directory enters or leaves an isolated try.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runEnv,
+}
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+}
+
+func runEnv(cmd *cobra.Command, args []string) error {
+	sh, err := shellSyntax(args[0])
+	if err != nil {
+		return err
+	}
+	var dir string
+	if len(args) == 2 && args[1] != "" {
+		var ok bool
+		if dir, ok = workspace.FindIsolated(args[1]); !ok {
+			return fmt.Errorf("%s is not in an isolated try", args[1])
+		}
+	}
+
+	active := os.Getenv(workspace.IsolatedEnv)
+	if dir == active {
+		return nil
+	}
+
+	// Values from before the active try, or the current ones
+	saved := map[string]*string{}
+	json.Unmarshal([]byte(os.Getenv(restoreEnv)), &saved)
+	original := func(name string) *string {
+		if active != "" {
+			return saved[name]
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			return &v
+		}
+		return nil
+	}
+
+	if active != "" {
+		names := make([]string, 0, len(saved))
+		for name := range saved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sh.set(name, saved[name])
+		}
+		sh.set(workspace.IsolatedEnv, nil)
+		sh.set(workspace.RealHomeEnv, nil)
+		sh.set(restoreEnv, nil)
+	}
+	if dir == "" {
+		return nil
+	}
+
+	vars := workspace.IsolatedVars(dir)
+	keep := map[string]*string{}
+	for _, v := range vars {
+		keep[v.Name] = original(v.Name)
+	}
+	data, err := json.Marshal(keep)
+	if err != nil {
+		return err
+	}
+	restore := string(data)
+	sh.set(restoreEnv, &restore)
+	if home := keep["HOME"]; home != nil {
+		sh.set(workspace.RealHomeEnv, home)
+	}
+	for _, v := range vars {
+		sh.set(v.Name, &v.Value)
+	}
+	sh.set(workspace.IsolatedEnv, &dir)
+	return nil
+}
+
+// shell prints variable assignments in the syntax of one shell.
+type shell struct {
+	export func(name, value string) string
+	unset  func(name string) string
+}
+
+// set prints an assignment, or removes the variable when value is nil.
+func (s shell) set(name string, value *string) {
+	if value == nil {
+		fmt.Println(s.unset(name))
+		return
+	}
+	fmt.Println(s.export(name, *value))
+}
+
+func shellSyntax(name string) (shell, error) {
+	switch name {
+	case "bash", "zsh":
+		return shell{
+			export: func(n, v string) string { return "export " + n + "=" + posixQuote(v) },
+			unset:  func(n string) string { return "unset " + n },
+		}, nil
+	case "fish":
+		return shell{
+			export: func(n, v string) string { return "set -gx " + n + " " + fishQuote(v) },
+			unset:  func(n string) string { return "set -e " + n },
+		}, nil
+	case "powershell", "pwsh":
+		return shell{
+			export: func(n, v string) string { return "$env:" + n + " = '" + strings.ReplaceAll(v, "'", "''") + "'" },
+			unset:  func(n string) string { return "Remove-Item -ErrorAction SilentlyContinue Env:" + n },
+		}, nil
+	}
+	return shell{}, fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish, powershell)", name)
+}
+
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
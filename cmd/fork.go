@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var flagForkBranch string
+
+var forkCmd = &cobra.Command{
+	Use:   "fork <try> [name]",
+	Short: "Duplicate a try, including its git history and uncommitted work",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runFork,
+}
+
+func init() {
+	forkCmd.Flags().StringVarP(&flagForkBranch, "branch", "b", "", "Start the fork on a new branch")
+	rootCmd.AddCommand(forkCmd)
+}
+
+func runFork(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	src, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	return handleFork(cfg, src, strings.Join(args[1:], " "), flagForkBranch)
+}
+
+// handleFork makes a new dated try next to src holding a copy of it. Git
+// repositories are cloned locally so objects are hardlinked, then the
+// working tree is copied over to carry uncommitted and untracked files.
+func handleFork(cfg *config.Config, src workspace.Directory, name, branch string) error {
+	if name == "" {
+		name = filepath.Base(src.NamePart)
+	}
+
+	if src.Archived() {
+		return fmt.Errorf("%s is archived, restore it first", src.Name)
+	}
+
+	rootPath := src.RootPath
+	path, err := workspace.Create(rootPath, name, cfg.Naming())
+	if err != nil {
+		return err
+	}
+
+	if err := copyForFork(src.Path, path); err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+
+	if branch != "" {
+		if !git.IsRepo(path) {
+			os.RemoveAll(path)
+			return fmt.Errorf("%s is not a git repository, cannot create branch %s", src.Name, branch)
+		}
+		if err := git.CheckoutNewBranch(path, branch); err != nil {
+			os.RemoveAll(path)
+			return err
+		}
+	}
+
+	rel, _ := filepath.Rel(rootPath, path)
+	err = workspace.UpdateMeta(rootPath, func(m *workspace.Meta) error {
+		entry := m.Entry(rel)
+		entry.Origin = workspace.OriginFork
+		entry.Parent = src.Path
+		entry.AddTags(src.Meta.Tags...)
+		entry.Visit(time.Now())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return enterTry(cfg, rel, path)
+}
+
+func copyForFork(srcPath, destPath string) error {
+	if !git.IsRepo(srcPath) {
+		return workspace.CopyTree(srcPath, destPath)
+	}
+
+	if err := git.CloneLocal(srcPath, destPath); err != nil {
+		return err
+	}
+	return workspace.CopyTreeFiltered(srcPath, destPath, func(rel string, d os.DirEntry) bool {
+		return rel == ".git"
+	})
+}
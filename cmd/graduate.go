@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagGraduateTo      string
+	flagGraduateRemote  string
+	flagGraduateMessage string
+)
+
+var graduateCmd = &cobra.Command{
+	Use:   "graduate <try>",
+	Short: "Move a try to a permanent project location",
+	Long: `Move a try out of the workspace into a permanent location (graduate.dir, ~/src by
+default), dropping its date prefix. The selector keeps pointing at the new location.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGraduate,
+}
+
+func init() {
+	graduateCmd.Flags().StringVar(&flagGraduateTo, "to", "", "Parent directory (default graduate.dir)")
+	graduateCmd.Flags().StringVar(&flagGraduateRemote, "remote", "", "Set origin to this URL")
+	graduateCmd.Flags().StringVar(&flagGraduateMessage, "message", "", "Replace gotry's initial commit message")
+	rootCmd.AddCommand(graduateCmd)
+}
+
+func runGraduate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if dir.Graduated() {
+		return fmt.Errorf("%s has already graduated to %s", dir.Name, dir.Path)
+	}
+	if dir.Archived() {
+		return fmt.Errorf("%s is archived, restore it first", dir.Name)
+	}
+
+	parent := cfg.Graduate.Dir
+	if flagGraduateTo != "" {
+		parent = config.ExpandHome(flagGraduateTo)
+	}
+	parent, err = filepath.Abs(parent)
+	if err != nil {
+		return err
+	}
+	dest := filepath.Join(parent, filepath.Base(dir.NamePart))
+
+	if err := workspace.MoveDir(dir.Path, dest); err != nil {
+		return err
+	}
+
+	err = workspace.UpdateMeta(dir.RootPath, func(m *workspace.Meta) error {
+		m.Entry(dir.Name).MovedTo = dest
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if git.IsRepo(dest) {
+		if err := git.RepairWorktrees(dest); err != nil {
+			return err
+		}
+		if flagGraduateMessage != "" {
+			rewritten, err := git.RewriteInitialCommit(dest, flagGraduateMessage)
+			if err != nil {
+				return err
+			}
+			if !rewritten {
+				fmt.Fprintln(os.Stderr, "gotry: --message not applied, the root commit was not made by gotry")
+			}
+		}
+		if flagGraduateRemote != "" {
+			if err := git.SetRemote(dest, "origin", flagGraduateRemote); err != nil {
+				return err
+			}
+		}
+	} else if flagGraduateRemote != "" || flagGraduateMessage != "" {
+		return fmt.Errorf("moved to %s, but it is not a git repository", dest)
+	}
+
+	fmt.Println(dest)
+	return nil
+}
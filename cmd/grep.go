@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagGrepIgnoreCase bool
+	flagGrepQuery      string
+	flagGrepMax        int
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern>",
+	Short: "Search file contents across tries",
+	Long: `Search the files of every try for a regular expression, printing the try, file,
+line number and matching line. Files excluded by .gitignore, binaries and artifact
+folders such as node_modules are skipped. Ctrl+G in the selector does the same
+interactively.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGrep,
+}
+
+func init() {
+	grepCmd.Flags().BoolVarP(&flagGrepIgnoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	grepCmd.Flags().StringVarP(&flagGrepQuery, "query", "q", "", "Only search tries matching this filter expression")
+	grepCmd.Flags().IntVarP(&flagGrepMax, "max-count", "m", 0, "Stop after this many matches per try")
+	rootCmd.AddCommand(grepCmd)
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	pattern := args[0]
+	if flagGrepIgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := queryTries(cfg, flagGrepQuery)
+	if err != nil {
+		return err
+	}
+
+	opts := workspace.GrepOptions{
+		Skip:    cfg.ArtifactNames(),
+		Limit:   flagGrepMax,
+		Content: workspace.LoadContentIndexes(dirs),
+	}
+	workspace.Grep(dirs, re, opts, func(hits []workspace.Hit) bool {
+		for _, h := range hits {
+			fmt.Printf("%s:%d: %s\n", filepath.Join(h.Dir.Name, h.Rel), h.Line, h.Text)
+		}
+		return true
+	})
+	return nil
+}
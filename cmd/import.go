@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagImportFrom   string
+	flagImportDryRun bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import tries from other tools",
+}
+
+var importTryCmd = &cobra.Command{
+	Use:   "try",
+	Short: "Take over a tobi/try directory",
+	Long: `Move every try from a tobi/try directory ($TRY_PATH, or ~/src/tries) into the
+workspace, renaming them to the configured naming scheme. Access history is
+seeded from directory times so tries rank as they did in try.`,
+	Args: cobra.NoArgs,
+	RunE: runImportTry,
+}
+
+func init() {
+	importTryCmd.Flags().StringVar(&flagImportFrom, "from", "", "tobi/try directory (default $TRY_PATH or ~/src/tries)")
+	importTryCmd.Flags().BoolVar(&flagImportDryRun, "dry-run", false, "Print the plan without changing anything")
+	importCmd.AddCommand(importTryCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImportTry(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	from := flagImportFrom
+	if from == "" {
+		from = os.Getenv("TRY_PATH")
+	}
+	if from == "" {
+		from = "~/src/tries"
+	}
+	from, err = filepath.Abs(config.ExpandHome(from))
+	if err != nil {
+		return err
+	}
+
+	// tobi/try names tries YYYY-MM-DD-name, gotry's default scheme
+	dirs, err := workspace.List(from, workspace.DefaultNaming)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("no tries found in %s", from)
+	}
+
+	root, err := cfg.DefaultRoot()
+	if err != nil {
+		return err
+	}
+	if !flagImportDryRun {
+		if err := os.MkdirAll(root.Path, 0755); err != nil {
+			return err
+		}
+	}
+
+	imported := 0
+	for _, dir := range dirs {
+		date := dir.Date
+		if date.IsZero() {
+			date = dir.ModTime
+		}
+		slug := workspace.Slugify(filepath.Base(dir.NamePart), cfg.Naming().MaxSlugLength)
+		if slug == "" {
+			fmt.Printf("skip %s: no usable name\n", dir.Path)
+			continue
+		}
+		rel, err := cfg.Naming().TryNameAt(slug, date)
+		if err != nil {
+			return err
+		}
+
+		dest := filepath.Join(root.Path, rel)
+		if flagImportDryRun {
+			fmt.Printf("%s → %s\n", dir.Path, dest)
+			continue
+		}
+
+		if dest != dir.Path {
+			dest, err = workspace.Reserve(dest)
+			if err != nil {
+				return err
+			}
+			if err := workspace.MoveInto(dir.Path, dest); err != nil {
+				fmt.Printf("skip %s: %v\n", dir.Path, err)
+				continue
+			}
+			// A copy across filesystems resets the time try ranked by
+			os.Chtimes(dest, dir.ModTime, dir.ModTime)
+		}
+
+		rel, _ = filepath.Rel(root.Path, dest)
+		err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+			entry := m.Entry(rel)
+			if entry.LastAccess.Before(dir.ModTime) {
+				entry.LastAccess = dir.ModTime
+			}
+			if entry.Visits == 0 {
+				entry.Visits = 1
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		imported++
+	}
+
+	if !flagImportDryRun {
+		fmt.Printf("Imported %d tries into %s\n", imported, root.Path)
+	}
+	return nil
+}
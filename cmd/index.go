@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagIndexRebuild bool
+	flagIndexContent bool
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Refresh the search index",
+	Long: `Listing a workspace goes through an index kept in .gotry/index.gob of each root,
+which caches directory entries, metadata and git state so that only directories
+that changed are read again. This command refreshes it, reading every directory
+again to pick up changes inside tries, and with --content also builds a trigram
+index of file contents that lets grep skip files that cannot match. Once built,
+the content index is kept up to date by every run of this command.`,
+	Args: cobra.NoArgs,
+	RunE: runIndex,
+}
+
+func init() {
+	indexCmd.Flags().BoolVar(&flagIndexRebuild, "rebuild", false, "Discard the indexes and build them from scratch")
+	indexCmd.Flags().BoolVar(&flagIndexContent, "content", false, "Build the content index for grep")
+	rootCmd.AddCommand(indexCmd)
+}
+
+func runIndex(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	roots := cfg.Roots()
+	if flagIndexRebuild {
+		for _, r := range roots {
+			if workspace.LoadContentIndex(r.Path) != nil {
+				flagIndexContent = true
+			}
+			if err := workspace.RemoveIndex(r.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	start := time.Now()
+	dirs, err := workspace.RefreshIndex(roots, cfg.Naming())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d tries in %s\n", len(dirs), time.Since(start).Round(time.Millisecond))
+
+	for _, r := range roots {
+		if err := indexContent(cfg, r, dirs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexContent refreshes the content index of a root, creating it when
+// --content is given.
+func indexContent(cfg *config.Config, root workspace.Root, dirs []workspace.Directory) error {
+	content := workspace.LoadContentIndex(root.Path)
+	if content == nil {
+		if !flagIndexContent {
+			return nil
+		}
+		content = workspace.NewContentIndex(root.Path)
+	}
+
+	start := time.Now()
+	var tries []workspace.Directory
+	read := 0
+	for _, d := range dirs {
+		if d.RootPath != root.Path || d.Archived() || d.Graduated() {
+			continue
+		}
+		n, err := content.Update(d, cfg.ArtifactNames())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gotry: %s: %v\n", d.Name, err)
+			continue
+		}
+		read += n
+		tries = append(tries, d)
+	}
+	content.Prune(tries)
+	if err := content.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Indexed contents of %d files in %s (%d read) in %s\n",
+		content.Files(), root.Name, read, time.Since(start).Round(time.Millisecond))
+	return nil
+}
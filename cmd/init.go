@@ -41,17 +41,42 @@ const bashZshInit = `# gotry shell integration
 
 gt() {
     local result
-    result=$(gotry "$@")
+    # The shell's pid lets temporary tries go when it exits
+    result=$(GOTRY_SESSION=$$ gotry "$@")
     local exit_code=$?
 
-    if [ $exit_code -eq 0 ] && [ -n "$result" ] && [ -d "$result" ]; then
-        cd "$result"
+    # A directory to change into, optionally followed by a file in it
+    local dir="${result%%$'\n'*}" file=""
+    [ "$dir" != "$result" ] && file="${result#*$'\n'}"
+    if [ $exit_code -eq 0 ] && [ -d "$dir" ] && { [ -z "$file" ] || [ -f "$file" ]; }; then
+        cd "$dir"
+        [ -n "$file" ] && echo "$file"
     elif [ -n "$result" ]; then
         echo "$result"
     fi
 
     return $exit_code
 }
+
+# Point HOME and the XDG directories into isolated tries while inside them
+_gotry_env() {
+    [ "$PWD" = "${_GOTRY_PWD-}" ] && return
+    _GOTRY_PWD=$PWD
+    local dir=$PWD
+    while [ -n "$dir" ] && [ ! -d "$dir/.isolated/home" ]; do
+        dir=${dir%/*}
+    done
+    [ "$dir" = "${GOTRY_ISOLATED-}" ] && return
+    eval "$(command gotry env bash "$dir")"
+}
+
+if [ -n "${ZSH_VERSION-}" ]; then
+    autoload -Uz add-zsh-hook
+    add-zsh-hook chpwd _gotry_env
+else
+    PROMPT_COMMAND="_gotry_env${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+_gotry_env
 `
 
 const fishInit = `# gotry shell integration
@@ -59,17 +84,32 @@ const fishInit = `# gotry shell integration
 #   gotry init fish | source
 
 function gt
-    set -l result (gotry $argv)
+    # The shell's pid lets temporary tries go when it exits
+    set -l result (env GOTRY_SESSION=$fish_pid gotry $argv)
     set -l exit_code $status
 
-    if test $exit_code -eq 0; and test -n "$result"; and test -d "$result"
-        cd "$result"
+    # A directory to change into, optionally followed by a file in it
+    set -l n (count $result)
+    if test $exit_code -eq 0; and test $n -ge 1; and test -d "$result[1]"; and begin; test $n -eq 1; or begin; test $n -eq 2; and test -f "$result[2]"; end; end
+        cd "$result[1]"
+        test $n -eq 2; and echo $result[2]
     else if test -n "$result"
-        echo "$result"
+        string join \n -- $result
     end
 
     return $exit_code
 end
+
+# Point HOME and the XDG directories into isolated tries while inside them
+function _gotry_env --on-variable PWD
+    set -l dir $PWD
+    while test -n "$dir"; and not test -d "$dir/.isolated/home"
+        set dir (string replace -r '/[^/]*$' '' -- $dir)
+    end
+    test "$dir" = "$GOTRY_ISOLATED"; and return
+    command gotry env fish "$dir" | source
+end
+_gotry_env
 `
 
 const powershellInit = `# gotry shell integration
@@ -77,15 +117,39 @@ const powershellInit = `# gotry shell integration
 #   gotry init powershell | Invoke-Expression
 
 function gt {
+    # The shell's pid lets temporary tries go when it exits
+    $env:GOTRY_SESSION = $PID
     $result = gotry @args
     $exitCode = $LASTEXITCODE
 
-    if ($exitCode -eq 0 -and $result -and (Test-Path -Path $result -PathType Container)) {
-        Set-Location $result
+    # A directory to change into, optionally followed by a file in it
+    $lines = @($result)
+    $isTarget = $lines.Count -ge 1 -and $lines[0] -and (Test-Path -Path $lines[0] -PathType Container)
+    if ($isTarget -and $lines.Count -eq 2) {
+        $isTarget = Test-Path -Path $lines[1] -PathType Leaf
+    } elseif ($lines.Count -gt 2) {
+        $isTarget = $false
+    }
+    if ($exitCode -eq 0 -and $isTarget) {
+        Set-Location $lines[0]
+        if ($lines.Count -eq 2) { Write-Output $lines[1] }
     } elseif ($result) {
         Write-Output $result
     }
 
     return $exitCode
 }
+
+# Point HOME and the XDG directories into isolated tries while inside them
+$global:GotryPrompt = $function:prompt
+function global:prompt {
+    $dir = (Get-Location).Path
+    while ($dir -and -not (Test-Path -Path (Join-Path $dir '.isolated/home') -PathType Container)) {
+        $dir = Split-Path -Path $dir -Parent
+    }
+    if ("$dir" -ne "$env:GOTRY_ISOLATED") {
+        gotry env powershell "$dir" | Out-String | Invoke-Expression
+    }
+    & $global:GotryPrompt
+}
 `
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagJournalSince string
+	flagJournalQuery string
+)
+
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Print recent notes of all tries as Markdown",
+	Long: `Print the notes written in the given period across all tries as a Markdown
+digest, one section per try with the most recently noted first.`,
+	Args: cobra.NoArgs,
+	RunE: runJournal,
+}
+
+func init() {
+	journalCmd.Flags().StringVar(&flagJournalSince, "since", "7d", "Period to cover, such as 1d, 2w or 36h")
+	journalCmd.Flags().StringVarP(&flagJournalQuery, "query", "q", "", "Only include tries matching this filter expression")
+	rootCmd.AddCommand(journalCmd)
+}
+
+type journalEntry struct {
+	dir   workspace.Directory
+	notes []workspace.Note
+}
+
+func runJournal(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	period, err := query.ParseDuration(flagJournalSince)
+	if err != nil {
+		return err
+	}
+	since := time.Now().Add(-period)
+
+	dirs, err := queryTries(cfg, flagJournalQuery)
+	if err != nil {
+		return err
+	}
+
+	var entries []journalEntry
+	for _, d := range dirs {
+		if d.Archived() {
+			continue
+		}
+		notes, err := workspace.ReadNotes(d)
+		if err != nil {
+			continue
+		}
+		var recent []workspace.Note
+		for _, n := range notes {
+			if !n.Time.Before(since) {
+				recent = append(recent, n)
+			}
+		}
+		if len(recent) > 0 {
+			entries = append(entries, journalEntry{d, recent})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return latest(entries[i].notes).After(latest(entries[j].notes))
+	})
+
+	fmt.Printf("# Journal since %s\n", since.Format("Mon 2 Jan 2006"))
+	if len(entries) == 0 {
+		fmt.Println("\nNo notes.")
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Printf("\n## %s", e.dir.Name)
+		for _, tag := range e.dir.Meta.Tags {
+			fmt.Printf(" #%s", tag)
+		}
+		fmt.Println()
+		if e.dir.Meta.Description != "" {
+			fmt.Printf("\n_%s_\n", e.dir.Meta.Description)
+		}
+		fmt.Println()
+		for _, n := range e.notes {
+			fmt.Printf("- **%s** %s\n", n.Time.Format("Mon 2 Jan 15:04"), indent(n.Text, "  "))
+		}
+	}
+	return nil
+}
+
+func latest(notes []workspace.Note) time.Time {
+	var t time.Time
+	for _, n := range notes {
+		if n.Time.After(t) {
+			t = n.Time
+		}
+	}
+	return t
+}
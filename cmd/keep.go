@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var keepCmd = &cobra.Command{
+	Use:   "keep <try> [name...]",
+	Short: "Move a temporary try into the workspace",
+	Long: `Move a temporary try into the default workspace root, optionally under a new
+name, so it is no longer removed when it expires.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runKeep,
+}
+
+func init() {
+	rootCmd.AddCommand(keepCmd)
+}
+
+func runKeep(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if !dir.Temporary() {
+		return fmt.Errorf("%s is not temporary", dir.Name)
+	}
+
+	root, err := cfg.DefaultRoot()
+	if err != nil {
+		return err
+	}
+	rel := dir.Name
+	if len(args) > 1 {
+		slug := workspace.Slugify(strings.Join(args[1:], " "), cfg.Naming().MaxSlugLength)
+		if slug == "" {
+			return fmt.Errorf("name %q has no usable characters", strings.Join(args[1:], " "))
+		}
+		if rel, err = cfg.Naming().TryName(slug); err != nil {
+			return err
+		}
+	}
+
+	// Reserve a free name, then move the try onto it
+	dest, err := workspace.Reserve(filepath.Join(root.Path, rel))
+	if err != nil {
+		return err
+	}
+	if err := workspace.MoveInto(dir.Path, dest); err != nil {
+		return err
+	}
+	if git.IsRepo(dest) {
+		if err := git.RepairWorktrees(dest); err != nil {
+			return err
+		}
+	}
+
+	entry := dir.Meta
+	entry.Expires, entry.Session = time.Time{}, 0
+	entry.Visit(time.Now())
+	rel, _ = filepath.Rel(root.Path, dest)
+	err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+		*m.Entry(rel) = entry
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := workspace.UpdateMeta(dir.RootPath, func(m *workspace.Meta) error {
+		m.Remove(dir.Name)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println(dest)
+	return nil
+}
+
+// tempExpiry returns when a temporary try made now expires, from --ttl or
+// tmp.ttl.
+func tempExpiry(cfg *config.Config) (time.Time, error) {
+	ttl := flagNewTTL
+	if ttl == "" {
+		ttl = cfg.Tmp.TTL
+	}
+	d, err := query.ParseDuration(ttl)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ttl: %w", err)
+	}
+	return time.Now().Add(d), nil
+}
+
+// tempSession returns the shell session a temporary try belongs to: the
+// shell function passes its pid, and without it gotry's parent is the
+// shell.
+func tempSession() int {
+	var pid int
+	if _, err := fmt.Sscan(os.Getenv("GOTRY_SESSION"), &pid); err == nil && pid > 0 {
+		return pid
+	}
+	return os.Getppid()
+}
+
+// expireTemp removes temporary tries that have expired or whose shell
+// session ended.
+func expireTemp(cfg *config.Config) {
+	tmp := cfg.TmpRoot()
+	dirs, err := workspace.List(tmp.Path, cfg.Naming())
+	if err != nil {
+		return
+	}
+	m, _ := cfg.Mux()
+	now := time.Now()
+	for _, d := range dirs {
+		if !d.Expired(now) {
+			continue
+		}
+		if err := workspace.Discard(d); err != nil {
+			fmt.Fprintf(os.Stderr, "gotry: %s: %v\n", d.Name, err)
+			continue
+		}
+		m.Kill(d.Name)
+		fmt.Fprintf(os.Stderr, "gotry: removed expired temporary try %s\n", d.Name)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagListQuery string
+	flagListPaths bool
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tries, optionally filtered by a query",
+	Long: `List tries, most recent first. --query takes the same expression as the search
+box, for example: gotry list --query 'age:>30d git:clean #spike'`,
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&flagListQuery, "query", "q", "", "Filter expression")
+	listCmd.Flags().BoolVar(&flagListPaths, "paths", false, "Print full paths only")
+	rootCmd.AddCommand(listCmd)
+}
+
+// queryTries lists every root and applies a query expression.
+func queryTries(cfg *config.Config, expr string) ([]workspace.Directory, error) {
+	q, err := query.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := workspace.ListRoots(cfg.Roots(), cfg.Naming())
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Filter(dirs, query.NewFacts()), nil
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := queryTries(cfg, flagListQuery)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dirs {
+		if flagListPaths {
+			fmt.Println(d.Path)
+			continue
+		}
+		fmt.Printf("%-45s %5s  %s\n", d.Name, workspace.RelativeTime(d.LastUsed()), d.Root)
+	}
+	return nil
+}
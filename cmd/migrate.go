@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMigrateFrom      string
+	flagMigrateCloneFrom string
+	flagMigrateDryRun    bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Rename existing tries to the configured naming scheme",
+	Long: `Rename existing tries from an old naming scheme to the one set in workspace.naming
+and workspace.clone_naming. Directories that are git repositories with an origin
+remote are renamed with the clone scheme.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&flagMigrateFrom, "from", workspace.DefaultTryScheme, "Naming scheme tries currently use")
+	migrateCmd.Flags().StringVar(&flagMigrateCloneFrom, "clone-from", workspace.DefaultCloneScheme, "Naming scheme clones currently use")
+	migrateCmd.Flags().BoolVar(&flagMigrateDryRun, "dry-run", false, "Print the renames without applying them")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	from, err := workspace.NewNaming(flagMigrateFrom, flagMigrateCloneFrom, cfg.Workspace.UTC)
+	if err != nil {
+		return err
+	}
+	to := cfg.Naming()
+
+	renamed := 0
+	for _, root := range cfg.Roots() {
+		dirs, err := workspace.List(root.Path, from)
+		if err != nil {
+			return err
+		}
+
+		for _, dir := range dirs {
+			parsed, ok := from.Parse(dir.Name)
+			if !ok {
+				continue
+			}
+
+			data := workspace.NameData{Time: parsed.Date, Slug: parsed.Slug}
+			if !parsed.HasDate {
+				data.Time = dir.ModTime
+			}
+
+			scheme := to.Try
+			if origin := git.OriginURL(dir.Path); origin != "" {
+				if info, err := git.ParseGitURL(origin); err == nil && info.Host != "" {
+					scheme = to.Clone
+					data.Host, data.User, data.Repo = info.Host, info.User, info.Repo
+				}
+			}
+
+			newName, err := scheme.Render(data)
+			if err != nil {
+				return err
+			}
+			if newName == dir.Name {
+				continue
+			}
+
+			fmt.Printf("%s → %s\n", dir.Path, newName)
+			if flagMigrateDryRun {
+				continue
+			}
+			// The metadata follows under the same lock; graduated and
+			// archived tries only have metadata here
+			err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+				if dir.Graduated() || dir.Archived() {
+					if m.Get(newName) != nil {
+						return fmt.Errorf("%s already exists", newName)
+					}
+				} else if err := workspace.Move(root.Path, dir.Name, newName); err != nil {
+					return err
+				}
+				m.Rename(dir.Name, newName)
+				return nil
+			})
+			if err != nil {
+				fmt.Printf("  skipped: %v\n", err)
+				continue
+			}
+			if !dir.Graduated() && !dir.Archived() {
+				if err := git.RepairWorktrees(filepath.Join(root.Path, newName)); err != nil {
+					fmt.Printf("  renamed, but repairing git worktrees failed: %v\n", err)
+				}
+			}
+			renamed++
+		}
+	}
+
+	if !flagMigrateDryRun {
+		fmt.Printf("Renamed %d tries\n", renamed)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagNewTmp  bool
+	flagNewTTL  string
+	flagNewLang []string
+)
+
+var newCmd = &cobra.Command{
+	Use:   "new <name>...",
+	Short: "Create a try without the selector",
+	Long: `Create a try and print its path for the shell function to change into.
+
+With --tmp the try goes into a temporary root, $XDG_RUNTIME_DIR/gotry or a folder
+in /tmp, and is removed once its TTL passes or the shell that made it exits,
+whichever comes first. "gotry keep" moves it into the workspace. In the selector,
+a name starting with ~ creates a temporary try.
+
+With --env the try gets an environment for the --lang project types: a
+flake.nix, shell.nix, devbox.json or .tool-versions with an .envrc, which direnv
+is allowed to load when it is installed.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runNew,
+}
+
+func init() {
+	newCmd.Flags().BoolVar(&flagNewTmp, "tmp", false, "Create a temporary try")
+	newCmd.Flags().StringVar(&flagNewTTL, "ttl", "", "Lifetime of a temporary try (default tmp.ttl)")
+	newCmd.Flags().BoolVar(&flagNoGit, "no-git", false, "Skip git initialization")
+	newCmd.Flags().BoolVar(&flagNoCommit, "no-commit", false, "Skip initial commit")
+	newCmd.Flags().StringVar(&flagTemplate, "template", "", "Template to copy into the try")
+	newCmd.Flags().BoolVar(&flagIsolate, "isolate", false, "Give the try its own HOME and XDG directories")
+	newCmd.Flags().StringVar(&flagEnv, "env", "", "Environment to bootstrap: flake, nix, devbox, asdf, direnv or none (default env.kind)")
+	newCmd.Flags().StringSliceVar(&flagNewLang, "lang", nil, "Project types whose template and environment packages to use, as in go,node")
+	rootCmd.AddCommand(newCmd)
+}
+
+func runNew(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+	expireTemp(cfg)
+
+	root, err := cfg.DefaultRoot()
+	if err != nil {
+		return err
+	}
+	if flagNewTmp {
+		root = cfg.TmpRoot()
+	}
+	return handleCreate(cfg, root, strings.Join(args, " "), nil, flagNewLang)
+}
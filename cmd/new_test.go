@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/viper"
+)
+
+// run executes gotry with args and returns what it wrote to stdout.
+func run(t *testing.T, args ...string) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+
+	viper.Reset()
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	os.Stdout = stdout
+	w.Close()
+	got := string(<-out)
+	if err != nil {
+		t.Fatalf("gotry %s: %v", strings.Join(args, " "), err)
+	}
+	return got
+}
+
+func TestNewPrintsOnlyPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(workspace.RealHomeEnv, "")
+	t.Setenv(config.ProfileEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(home, "run"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "gotry")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "gotry@example.com")
+	}
+	t.Cleanup(viper.Reset)
+
+	for _, args := range [][]string{
+		{"new", "demo"},
+		{"new", "--tmp", "scratch"},
+	} {
+		out := run(t, args...)
+		path := strings.TrimSuffix(out, "\n")
+		if strings.Contains(path, "\n") || !filepath.IsAbs(path) {
+			t.Fatalf("gotry %s printed %q, want only the new try's path", strings.Join(args, " "), out)
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			t.Errorf("gotry %s: %v", strings.Join(args, " "), err)
+		}
+	}
+}
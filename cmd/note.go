@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var flagNoteList bool
+
+var noteCmd = &cobra.Command{
+	Use:   "note <try> [text...]",
+	Short: "Add a timestamped note to a try",
+	Long: `Append a timestamped entry to the NOTES.md file of a try. Without text the
+notes open in $EDITOR under a fresh heading; "-" reads the entry from stdin.
+Ctrl+E in the selector opens the notes of the highlighted try.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runNote,
+}
+
+func init() {
+	noteCmd.Flags().BoolVarP(&flagNoteList, "list", "l", false, "Print the notes of the try")
+	rootCmd.AddCommand(noteCmd)
+}
+
+func runNote(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	if flagNoteList {
+		notes, err := workspace.ReadNotes(dir)
+		if err != nil {
+			return err
+		}
+		for _, n := range notes {
+			fmt.Printf("%s  %s\n", n.Time.Format("2006-01-02 15:04"), indent(n.Text, "                  "))
+		}
+		return nil
+	}
+
+	text := strings.Join(args[1:], " ")
+	if text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	path, err := workspace.StartNote(dir, text, time.Now())
+	if err != nil || text != "" {
+		return err
+	}
+	return runInTry(dir.Path, config.WithPath(cfg.Editor(), path))
+}
+
+// indent prefixes every line after the first.
+func indent(text, prefix string) string {
+	return strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
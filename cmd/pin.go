@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <try>",
+	Short: "Pin a try so queries can keep it with 'pinned' or skip it with '-pinned'",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateTryMeta(args[0], func(t *workspace.TryMeta) {
+			t.Pinned = true
+		})
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <try>",
+	Short: "Unpin a try",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateTryMeta(args[0], func(t *workspace.TryMeta) {
+			t.Pinned = false
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd, unpinCmd)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagPruneQuery  string
+	flagPruneDryRun bool
+	flagPruneYes    bool
+)
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune --query <expr>",
+	Short: "Delete every try matching a query",
+	Long: `Delete every try matching a query expression, for example:
+  gotry prune --query 'age:>90d -pinned git:clean'`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+func init() {
+	pruneCmd.Flags().StringVarP(&flagPruneQuery, "query", "q", "", "Filter expression (required)")
+	pruneCmd.Flags().BoolVar(&flagPruneDryRun, "dry-run", false, "List matching tries without deleting them")
+	pruneCmd.Flags().BoolVarP(&flagPruneYes, "yes", "y", false, "Do not ask for confirmation")
+	pruneCmd.MarkFlagRequired("query")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	if strings.TrimSpace(flagPruneQuery) == "" {
+		return fmt.Errorf("refusing to prune with an empty query")
+	}
+
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dirs, err := queryTries(cfg, flagPruneQuery)
+	if err != nil {
+		return err
+	}
+
+	if len(dirs) == 0 {
+		fmt.Println("No tries match")
+		return nil
+	}
+
+	for _, d := range dirs {
+		fmt.Println(d.Path)
+	}
+	if flagPruneDryRun {
+		return nil
+	}
+
+	if !flagPruneYes {
+		fmt.Printf("Delete %d tries? [y/N] ", len(dirs))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			return nil
+		}
+	}
+
+	return deleteTries(cfg, dirs)
+}
+
+// deleteTries removes tries, forgets their metadata and ends their
+// multiplexer sessions.
+func deleteTries(cfg *config.Config, dirs []workspace.Directory) error {
+	m, err := cfg.Mux()
+	if err != nil {
+		return err
+	}
+	for _, d := range dirs {
+		if err := workspace.Discard(d); err != nil {
+			return err
+		}
+		if err := m.Kill(d.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "gotry: %s: %v\n", d.Name, err)
+		}
+	}
+	return nil
+}
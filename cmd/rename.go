@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <try> <new name>",
+	Short: "Rename a try, keeping its date prefix and metadata",
+	Args:  cobra.MinimumNArgs(2),
+	RunE:  runRename,
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	name, err := workspace.Rename(dir, strings.Join(args[1:], " "), cfg.Naming())
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir.RootPath, name)
+	if err := git.RepairWorktrees(path); err != nil {
+		return fmt.Errorf("renamed, but repairing git worktrees failed: %w", err)
+	}
+
+	fmt.Println(path)
+	return nil
+}
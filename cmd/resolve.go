@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+)
+
+// findTry resolves a command-line reference to a try: a path, a name
+// relative to a workspace root, or a substring matching exactly one try.
+func findTry(cfg *config.Config, ref string) (workspace.Directory, error) {
+	dirs, err := workspace.ListRoots(cfg.Roots(), cfg.Naming())
+	if err != nil {
+		return workspace.Directory{}, err
+	}
+
+	if abs, err := filepath.Abs(ref); err == nil {
+		for _, d := range dirs {
+			if d.Path == abs {
+				return d, nil
+			}
+		}
+	}
+
+	ref = filepath.FromSlash(strings.TrimSuffix(ref, "/"))
+
+	var exact, partial []workspace.Directory
+	for _, d := range dirs {
+		switch {
+		case d.Name == ref || d.NamePart == ref:
+			exact = append(exact, d)
+		case strings.Contains(d.Name, ref):
+			partial = append(partial, d)
+		}
+	}
+
+	for _, candidates := range [][]workspace.Directory{exact, partial} {
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			names := make([]string, len(candidates))
+			for i, d := range candidates {
+				names[i] = d.Name
+			}
+			return workspace.Directory{}, fmt.Errorf("%q is ambiguous: %s", ref, strings.Join(names, ", "))
+		}
+	}
+
+	return workspace.Directory{}, fmt.Errorf("no try matches %q", ref)
+}
@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/raiden076/gotry/internal/config"
 	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/hooks"
 	"github.com/raiden076/gotry/internal/tui"
 	"github.com/raiden076/gotry/internal/workspace"
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +22,10 @@ var (
 	flagNoGit    bool
 	flagNoCommit bool
 	flagPath     string
+	flagTemplate string
+	flagProfile  string
+	flagIsolate  bool
+	flagEnv      string
 )
 
 var rootCmd = &cobra.Command{
@@ -37,16 +46,20 @@ func init() {
 	rootCmd.Flags().BoolVar(&flagNoGit, "no-git", false, "Skip git initialization")
 	rootCmd.Flags().BoolVar(&flagNoCommit, "no-commit", false, "Skip initial commit")
 	rootCmd.Flags().StringVar(&flagPath, "path", "", "Override workspace path")
+	rootCmd.Flags().StringVar(&flagTemplate, "template", "", "Template to copy into new tries")
+	rootCmd.Flags().BoolVar(&flagIsolate, "isolate", false, "Give new tries their own HOME and XDG directories")
+	rootCmd.Flags().StringVar(&flagEnv, "env", "", "Environment to bootstrap new tries with: flake, nix, devbox, asdf, direnv or none (default env.kind)")
+	rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "Configuration profile (default $"+config.ProfileEnv+")")
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
+	cfg, err := config.Load(flagProfile)
 	if err != nil {
 		return err
 	}
 
 	if flagPath != "" {
-		cfg.Workspace.Path = flagPath
+		cfg.SetPath(flagPath)
 	}
 
 	if err := cfg.EnsureWorkspaceExists(); err != nil {
@@ -58,13 +71,16 @@ func runRoot(cmd *cobra.Command, args []string) error {
 		return handleClone(cfg, args[0])
 	}
 
+	autoClean(cfg)
+	expireTemp(cfg)
+
 	// Launch TUI
 	initialQuery := ""
 	if len(args) == 1 {
 		initialQuery = args[0]
 	}
 
-	model := tui.NewModel(cfg.Workspace.Path, initialQuery)
+	model := tui.NewModel(cfg, initialQuery)
 	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithOutput(os.Stderr))
 
 	finalModel, err := p.Run()
@@ -73,6 +89,9 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	}
 
 	m := finalModel.(tui.Model)
+	if dirs, sizes := m.Sizes(); sizes != nil {
+		checkQuota(cfg, dirs, sizes)
+	}
 
 	if m.Quitting() && m.Selected() == "" {
 		return nil // User cancelled
@@ -83,23 +102,154 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	// Handle create new directory
 	if strings.HasPrefix(selected, "CREATE:") {
 		name := strings.TrimPrefix(selected, "CREATE:")
-		return handleCreate(cfg, name)
+		return handleCreate(cfg, m.CreateRoot(), name, m.CreateTags(), m.CreateTypes())
+	}
+
+	// Handle fork of the selected try
+	if strings.HasPrefix(selected, "FORK:") {
+		name := strings.TrimPrefix(selected, "FORK:")
+		return handleFork(cfg, m.SelectedDir(), name, "")
 	}
 
 	// Output selected path for shell integration
 	if selected != "" {
-		fmt.Println(selected)
+		if dir := m.SelectedDir(); dir.RootPath != "" {
+			if dir.Archived() {
+				if err := workspace.RestoreTry(dir); err != nil {
+					return err
+				}
+			}
+			recordVisit(dir.RootPath, dir.Name)
+		}
+
+		// Editors and subshells print nothing, so the shell function
+		// stays where it is
+		if path, ok := strings.CutPrefix(selected, "EDIT:"); ok {
+			return runInTry(path, config.WithPath(cfg.Editor(), path))
+		}
+		if path, ok := strings.CutPrefix(selected, "SHELL:"); ok {
+			// The shell exits with the status of its last command
+			var exitErr *exec.ExitError
+			if err := runInTry(path, cfg.Shell()); err != nil && !errors.As(err, &exitErr) {
+				return err
+			}
+			return nil
+		}
+
+		// A content search hit also names the file; the shell function
+		// changes into the try and echoes it
+		if file := m.SelectedFile(); file != "" {
+			fmt.Println(selected)
+			fmt.Println(file)
+			return nil
+		}
+		return enterTry(cfg, m.SelectedDir().Name, selected)
 	}
 
 	return nil
 }
 
-func handleCreate(cfg *config.Config, name string) error {
-	path, err := workspace.Create(cfg.Workspace.Path, name)
+// runInTry runs a command in a try on the terminal, keeping stdout free
+// for the shell wrapper.
+func runInTry(path string, command []string) error {
+	c := exec.Command(command[0], command[1:]...)
+	c.Dir = path
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+func handleCreate(cfg *config.Config, root workspace.Root, name string, tags, types []string) error {
+	env := flagEnv
+	if env == "" {
+		env = cfg.Env.Kind
+	}
+	if env == "none" {
+		env = ""
+	}
+	if _, ok := workspace.LookupEnv(env); env != "" && !ok {
+		return fmt.Errorf("unknown environment %q (supported: flake, nix, devbox, asdf, direnv)", env)
+	}
+
+	// Tries in the temporary root expire; the root itself is private
+	var expires time.Time
+	if root == cfg.TmpRoot() {
+		var err error
+		if expires, err = tempExpiry(cfg); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(root.Path, 0700); err != nil {
+			return err
+		}
+	}
+
+	path, err := workspace.Create(root.Path, name, cfg.Naming())
+	if err != nil {
+		return err
+	}
+
+	rel, _ := filepath.Rel(root.Path, path)
+	err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+		entry := m.Entry(rel)
+		entry.Origin = workspace.OriginNew
+		entry.Visit(time.Now())
+		entry.AddTags(tags...)
+		if !expires.IsZero() {
+			entry.Expires = expires
+			entry.Session = tempSession()
+		}
+		entry.Isolated = flagIsolate
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
+	if flagIsolate {
+		if err := workspace.Isolate(path); err != nil {
+			return err
+		}
+	}
+
+	// Template
+	template := flagTemplate
+	for _, kind := range types {
+		if template != "" {
+			break
+		}
+		if template = cfg.TemplateForType(kind); template == "" {
+			fmt.Fprintf(os.Stderr, "gotry: no template for %s\n", kind)
+		}
+	}
+	if template == "" {
+		template = cfg.Templates.Default
+	}
+	if template != "" {
+		if err := workspace.CopyTree(cfg.TemplatePath(template), path); err != nil {
+			return fmt.Errorf("template %s: %w", template, err)
+		}
+	}
+
+	// Environment, bootstrapped or brought by the template
+	if env != "" {
+		if err := workspace.Bootstrap(path, env, types, cfg.EnvPackages()); err != nil {
+			return err
+		}
+	}
+	if kind := workspace.DetectEnv(path); kind != "" {
+		err := workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+			m.Entry(rel).Env = kind
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := workspace.AllowDirenv(path); err != nil {
+			fmt.Fprintf(os.Stderr, "gotry: direnv allow: %v\n", err)
+		}
+	}
+
 	// Git init
 	if cfg.Git.AutoInit && !flagNoGit {
 		if err := git.Init(path); err != nil {
@@ -114,8 +264,11 @@ func handleCreate(cfg *config.Config, name string) error {
 		}
 	}
 
-	fmt.Println(path)
-	return nil
+	if err := hooks.Run(cfg.Hooks.PostCreate, path); err != nil {
+		return err
+	}
+
+	return enterTry(cfg, rel, path)
 }
 
 func handleClone(cfg *config.Config, url string) error {
@@ -124,24 +277,66 @@ func handleClone(cfg *config.Config, url string) error {
 		return err
 	}
 
-	dirName := info.DirectoryName()
-	destPath := cfg.Workspace.Path + "/" + dirName
+	dirName, err := cfg.Naming().CloneName(info.Host, info.User, info.Repo)
+	if err != nil {
+		return err
+	}
+	root, err := cfg.CloneRoot()
+	if err != nil {
+		return err
+	}
+	destPath := filepath.Join(root.Path, dirName)
+	if !workspace.Within(root.Path, destPath) {
+		return fmt.Errorf("repository %s resolves outside %s", url, root.Path)
+	}
 
-	// Handle collision
-	counter := 2
-	basePath := destPath
-	for {
-		if _, err := os.Stat(destPath); os.IsNotExist(err) {
-			break
-		}
-		destPath = fmt.Sprintf("%s-%d", basePath, counter)
-		counter++
+	// Reserve the directory first so concurrent clones never share it
+	destPath, err = workspace.Reserve(destPath)
+	if err != nil {
+		return err
 	}
 
 	if err := git.Clone(url, destPath); err != nil {
+		os.RemoveAll(destPath)
 		return err
 	}
 
-	fmt.Println(destPath)
+	rel, _ := filepath.Rel(root.Path, destPath)
+	err = workspace.UpdateMeta(root.Path, func(m *workspace.Meta) error {
+		entry := m.Entry(rel)
+		entry.Origin = workspace.OriginClone
+		entry.Visit(time.Now())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return enterTry(cfg, rel, destPath)
+}
+
+// enterTry hands a try over to the shell: the path is printed for the
+// shell function to change into, unless the try opens in a multiplexer
+// session.
+func enterTry(cfg *config.Config, name, path string) error {
+	m, err := cfg.Mux()
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		if opened, err := m.Open(name, path); opened || err != nil {
+			return err
+		}
+	}
+	fmt.Println(path)
 	return nil
 }
+
+// recordVisit adds an entry to a try's access history. Failures are not
+// worth interrupting the user for.
+func recordVisit(rootPath, name string) {
+	workspace.UpdateMeta(rootPath, func(m *workspace.Meta) error {
+		m.Entry(name).Visit(time.Now())
+		return nil
+	})
+}
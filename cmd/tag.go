@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Manage try tags",
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <try> <tag>...",
+	Short: "Add tags to a try",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateTryMeta(args[0], func(t *workspace.TryMeta) {
+			t.AddTags(args[1:]...)
+		})
+	},
+}
+
+var tagRmCmd = &cobra.Command{
+	Use:   "rm <try> <tag>...",
+	Short: "Remove tags from a try",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateTryMeta(args[0], func(t *workspace.TryMeta) {
+			t.RemoveTags(args[1:]...)
+		})
+	},
+}
+
+var tagLsCmd = &cobra.Command{
+	Use:   "ls [try]",
+	Short: "List tags of a try, or all tags in use",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTagLs,
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <try> [text...]",
+	Short: "Set or clear the one-line description of a try",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateTryMeta(args[0], func(t *workspace.TryMeta) {
+			t.Description = strings.Join(args[1:], " ")
+		})
+	},
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd, tagRmCmd, tagLsCmd)
+	rootCmd.AddCommand(tagCmd, describeCmd)
+}
+
+func updateTryMeta(ref string, fn func(*workspace.TryMeta)) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	dir, err := findTry(cfg, ref)
+	if err != nil {
+		return err
+	}
+
+	return workspace.UpdateMeta(dir.RootPath, func(m *workspace.Meta) error {
+		fn(m.Entry(dir.Name))
+		return nil
+	})
+}
+
+func runTagLs(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(flagProfile)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		dir, err := findTry(cfg, args[0])
+		if err != nil {
+			return err
+		}
+		for _, tag := range dir.Meta.Tags {
+			fmt.Println(tag)
+		}
+		return nil
+	}
+
+	dirs, err := workspace.ListRoots(cfg.Roots(), cfg.Naming())
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, d := range dirs {
+		for _, tag := range d.Meta.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		fmt.Printf("%-20s %d\n", tag, counts[tag])
+	}
+	return nil
+}
@@ -1,18 +1,55 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/raiden076/gotry/internal/mux"
+	"github.com/raiden076/gotry/internal/workspace"
 	"github.com/spf13/viper"
 )
 
+// ProfileEnv selects a profile when --profile is not given.
+const ProfileEnv = "GOTRY_PROFILE"
+
 type Config struct {
-	Workspace WorkspaceConfig `mapstructure:"workspace"`
-	Git       GitConfig       `mapstructure:"git"`
+	Workspace   WorkspaceConfig          `mapstructure:"workspace"`
+	Git         GitConfig                `mapstructure:"git"`
+	Hooks       HooksConfig              `mapstructure:"hooks"`
+	Templates   TemplatesConfig          `mapstructure:"templates"`
+	Graduate    GraduateConfig           `mapstructure:"graduate"`
+	Archive     ArchiveConfig            `mapstructure:"archive"`
+	Clean       CleanConfig              `mapstructure:"clean"`
+	UI          UIConfig                 `mapstructure:"ui"`
+	Actions     ActionsConfig            `mapstructure:"actions"`
+	Integration IntegrationConfig        `mapstructure:"integration"`
+	Tmp         TmpConfig                `mapstructure:"tmp"`
+	Env         EnvConfig                `mapstructure:"env"`
+	Profiles    map[string]ProfileConfig `mapstructure:"profiles"`
+
+	// Profile is the name of the active profile, empty when none applies.
+	Profile string `mapstructure:"-"`
+
+	naming *workspace.Naming
 }
 
 type WorkspaceConfig struct {
+	Path          string       `mapstructure:"path"`
+	Paths         []RootConfig `mapstructure:"paths"`
+	Default       string       `mapstructure:"default"`
+	CloneRoot     string       `mapstructure:"clone_root"`
+	Naming        string       `mapstructure:"naming"`
+	CloneNaming   string       `mapstructure:"clone_naming"`
+	UTC           bool         `mapstructure:"utc"`
+	MaxNameLength int          `mapstructure:"max_name_length"`
+	Quota         string       `mapstructure:"quota"`
+}
+
+type RootConfig struct {
+	Name string `mapstructure:"name"`
 	Path string `mapstructure:"path"`
 }
 
@@ -21,8 +58,75 @@ type GitConfig struct {
 	InitialCommit bool `mapstructure:"initial_commit"`
 }
 
+type HooksConfig struct {
+	PostCreate []string `mapstructure:"post_create"`
+}
+
+type TemplatesConfig struct {
+	Dir     string            `mapstructure:"dir"`
+	Default string            `mapstructure:"default"`
+	Types   map[string]string `mapstructure:"types"` // project type to template
+}
+
+type GraduateConfig struct {
+	Dir string `mapstructure:"dir"`
+}
+
+type UIConfig struct {
+	Icons string `mapstructure:"icons"` // auto, emoji or ascii
+}
+
+// ActionsConfig holds the commands behind the selector's actions. A
+// {path} argument is replaced by the try's path; without one the path is
+// appended.
+type ActionsConfig struct {
+	Editor string `mapstructure:"editor"` // default $VISUAL or $EDITOR
+	Shell  string `mapstructure:"shell"`  // default $SHELL
+	Copy   string `mapstructure:"copy"`   // reads the path on stdin; OSC52 when empty
+}
+
+type TmpConfig struct {
+	Dir string `mapstructure:"dir"` // default $XDG_RUNTIME_DIR/gotry or a folder in /tmp
+	TTL string `mapstructure:"ttl"` // lifetime of temporary tries, as in 12h or 2d
+}
+
+// EnvConfig controls environment bootstrapping. Entries in Packages
+// replace the built-in packages for the same project type.
+type EnvConfig struct {
+	Kind     string                           `mapstructure:"kind"` // flake, nix, devbox, asdf or direnv; none when empty
+	Packages map[string]workspace.EnvPackages `mapstructure:"packages"`
+}
+
+type IntegrationConfig struct {
+	Multiplexer string `mapstructure:"multiplexer"` // tmux or zellij
+	Mode        string `mapstructure:"mode"`        // session, or window for tmux
+	Layout      string `mapstructure:"layout"`      // applied to new sessions
+	Socket      string `mapstructure:"socket"`      // tmux -L socket name
+}
+
+type ArchiveConfig struct {
+	Dir     string   `mapstructure:"dir"`
+	Format  string   `mapstructure:"format"`
+	Slim    bool     `mapstructure:"slim"`
+	Exclude []string `mapstructure:"exclude"`
+}
+
+// CleanConfig controls artifact purging. Entries in Artifacts replace the
+// built-in rule for the same ecosystem; one without names disables it.
+type CleanConfig struct {
+	AfterDays int                               `mapstructure:"after_days"`
+	Artifacts map[string]workspace.ArtifactRule `mapstructure:"artifacts"`
+}
+
+// ProfileConfig holds the profile-only keys. Everything else under
+// [profiles.<name>] is merged over the top-level settings when the profile
+// is active.
+type ProfileConfig struct {
+	Directories []string `mapstructure:"directories"`
+}
+
 func DefaultConfig() *Config {
-	homeDir, _ := os.UserHomeDir()
+	homeDir, _ := UserHomeDir()
 	return &Config{
 		Workspace: WorkspaceConfig{
 			Path: filepath.Join(homeDir, "tries"),
@@ -31,16 +135,38 @@ func DefaultConfig() *Config {
 			AutoInit:      true,
 			InitialCommit: true,
 		},
+		Templates: TemplatesConfig{
+			Dir: filepath.Join(homeDir, ".config", "gotry", "templates"),
+		},
+		Graduate: GraduateConfig{
+			Dir: filepath.Join(homeDir, "src"),
+		},
+		Archive: ArchiveConfig{
+			Format:  workspace.FormatGzip,
+			Exclude: workspace.DefaultArtifactDirs,
+		},
+		Tmp: TmpConfig{
+			TTL: "24h",
+		},
 	}
 }
 
-func Load() (*Config, error) {
+// Path returns the location of the config file, whether or not it exists.
+func Path() string {
+	homeDir, _ := UserHomeDir()
+	return filepath.Join(homeDir, ".config", "gotry", "config.toml")
+}
+
+// Load reads the config file and applies the selected profile. An empty
+// profile falls back to $GOTRY_PROFILE, then to the first profile whose
+// directories contain the current working directory.
+func Load(profile string) (*Config, error) {
 	cfg := DefaultConfig()
 
 	viper.SetConfigName("config")
 	viper.SetConfigType("toml")
 
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := UserHomeDir()
 	if err != nil {
 		return cfg, nil // Return defaults if home dir unavailable
 	}
@@ -50,6 +176,9 @@ func Load() (*Config, error) {
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			if profile != "" {
+				return nil, fmt.Errorf("unknown profile: %s", profile)
+			}
 			return cfg, nil // Config file not found, use defaults
 		}
 		return nil, err
@@ -59,14 +188,365 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
-	// Expand ~ in workspace path
-	if cfg.Workspace.Path[:2] == "~/" {
-		cfg.Workspace.Path = filepath.Join(homeDir, cfg.Workspace.Path[2:])
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile == "" {
+		profile = cfg.matchProfile()
+	}
+
+	if profile != "" {
+		profile = strings.ToLower(profile)
+		sub := viper.Sub("profiles." + profile)
+		if sub == nil {
+			return nil, fmt.Errorf("unknown profile: %s", profile)
+		}
+		overrides := sub.AllSettings()
+		delete(overrides, "directories")
+		if err := viper.MergeConfigMap(overrides); err != nil {
+			return nil, err
+		}
+		if err := viper.Unmarshal(cfg); err != nil {
+			return nil, err
+		}
+		// A profile's own path replaces the inherited roots, which the
+		// inherited default and clone_root name
+		if sub.IsSet("workspace.path") && !sub.IsSet("workspace.paths") {
+			cfg.Workspace.Paths = nil
+			if !sub.IsSet("workspace.default") {
+				cfg.Workspace.Default = ""
+			}
+			if !sub.IsSet("workspace.clone_root") {
+				cfg.Workspace.CloneRoot = ""
+			}
+		}
+		cfg.Profile = profile
+	}
+
+	cfg.Workspace.Path = ExpandHome(cfg.Workspace.Path)
+	for i := range cfg.Workspace.Paths {
+		cfg.Workspace.Paths[i].Path = ExpandHome(cfg.Workspace.Paths[i].Path)
+	}
+	cfg.Templates.Dir = ExpandHome(cfg.Templates.Dir)
+	cfg.Graduate.Dir = ExpandHome(cfg.Graduate.Dir)
+	cfg.Archive.Dir = ExpandHome(cfg.Archive.Dir)
+
+	cfg.naming, err = workspace.NewNaming(cfg.Workspace.Naming, cfg.Workspace.CloneNaming, cfg.Workspace.UTC)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Workspace.MaxNameLength > 0 {
+		cfg.naming.MaxSlugLength = cfg.Workspace.MaxNameLength
+	}
+
+	if _, err := cfg.DefaultRoot(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.CloneRoot(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// Naming returns the parsed directory naming schemes.
+func (c *Config) Naming() *workspace.Naming {
+	if c.naming == nil {
+		return workspace.DefaultNaming
+	}
+	return c.naming
+}
+
+// matchProfile picks the profile with the most specific directory
+// containing the current working directory.
+func (c *Config) matchProfile() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	best, bestLen := "", 0
+	for name, p := range c.Profiles {
+		for _, dir := range p.Directories {
+			dir = ExpandHome(dir)
+			rel, err := filepath.Rel(dir, cwd)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			if len(dir) > bestLen {
+				best, bestLen = name, len(dir)
+			}
+		}
+	}
+	return best
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TemplatePath resolves a template name to its directory.
+func (c *Config) TemplatePath(name string) string {
+	if name == "" {
+		return ""
+	}
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "~/") {
+		return ExpandHome(name)
+	}
+	return filepath.Join(c.Templates.Dir, name)
+}
+
+// TemplateForType picks the template for a project type: the one set in
+// templates.types, or else the first template directory detected as that
+// type. It returns "" when there is none.
+func (c *Config) TemplateForType(kind string) string {
+	kind = strings.ToLower(kind)
+	if name, ok := c.Templates.Types[kind]; ok {
+		return name
+	}
+
+	entries, err := os.ReadDir(c.Templates.Dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		for _, t := range workspace.DetectTypes(filepath.Join(c.Templates.Dir, e.Name())) {
+			if t == kind {
+				return e.Name()
+			}
+		}
+	}
+	return ""
+}
+
+// ArchiveDir returns where archives of tries in root are written, by
+// default the root's own .gotry/archive.
+func (c *Config) ArchiveDir(root string) string {
+	if c.Archive.Dir != "" {
+		return c.Archive.Dir
+	}
+	return filepath.Join(root, workspace.MetaDir, "archive")
+}
+
+// ArtifactRules returns the artifact catalogue with configured overrides
+// applied.
+func (c *Config) ArtifactRules() map[string]workspace.ArtifactRule {
+	rules := make(map[string]workspace.ArtifactRule, len(workspace.DefaultArtifactRules))
+	for eco, rule := range workspace.DefaultArtifactRules {
+		rules[eco] = rule
+	}
+	for eco, rule := range c.Clean.Artifacts {
+		if len(rule.Names) == 0 {
+			delete(rules, eco)
+			continue
+		}
+		rules[eco] = rule
+	}
+	return rules
+}
+
+// EnvPackages returns the environment packages of each project type with
+// configured overrides applied.
+func (c *Config) EnvPackages() map[string]workspace.EnvPackages {
+	packages := make(map[string]workspace.EnvPackages, len(workspace.DefaultEnvPackages))
+	for kind, p := range workspace.DefaultEnvPackages {
+		packages[kind] = p
+	}
+	for kind, p := range c.Env.Packages {
+		packages[kind] = p
+	}
+	return packages
+}
+
+// ArtifactNames returns the folder name patterns of every artifact rule.
+func (c *Config) ArtifactNames() []string {
+	var names []string
+	for _, rule := range c.ArtifactRules() {
+		names = append(names, rule.Names...)
+	}
+	return names
+}
+
+// Editor returns the command line of actions.editor or the user's editor
+// from $VISUAL or $EDITOR, falling back to vi.
+func (c *Config) Editor() []string {
+	if fields := strings.Fields(c.Actions.Editor); len(fields) > 0 {
+		return fields
+	}
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// Mux returns the multiplexer tries are opened in, or nil when none is
+// configured.
+func (c *Config) Mux() (*mux.Mux, error) {
+	i := c.Integration
+	return mux.New(i.Multiplexer, i.Mode, ExpandHome(i.Layout), i.Socket)
+}
+
+// Shell returns the command line of actions.shell or $SHELL, falling back
+// to sh.
+func (c *Config) Shell() []string {
+	if fields := strings.Fields(c.Actions.Shell); len(fields) > 0 {
+		return fields
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return []string{shell}
+	}
+	return []string{"sh"}
+}
+
+// CopyCommand returns the command line of actions.copy, or nil to copy
+// through the terminal with OSC52.
+func (c *Config) CopyCommand() []string {
+	return strings.Fields(c.Actions.Copy)
+}
+
+// WithPath substitutes path for {path} in a command line, or appends it
+// when the command has no {path}.
+func WithPath(command []string, path string) []string {
+	out := make([]string, 0, len(command)+1)
+	found := false
+	for _, arg := range command {
+		if strings.Contains(arg, "{path}") {
+			arg = strings.ReplaceAll(arg, "{path}", path)
+			found = true
+		}
+		out = append(out, arg)
+	}
+	if !found {
+		out = append(out, path)
+	}
+	return out
+}
+
+// UserHomeDir returns the user's home directory, seeing through the home
+// of an isolated try the shell may have switched to.
+func UserHomeDir() (string, error) {
+	if home := os.Getenv(workspace.RealHomeEnv); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
+
+// ExpandHome replaces a leading ~/ with the user's home directory.
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, path[2:])
+}
+
+// Roots returns the workspace roots searched by the selector. Without
+// workspace.paths the single workspace.path is used. The temporary root
+// comes last once it exists.
+func (c *Config) Roots() []workspace.Root {
+	var roots []workspace.Root
+	if len(c.Workspace.Paths) == 0 {
+		roots = []workspace.Root{{
+			Name: filepath.Base(c.Workspace.Path),
+			Path: c.Workspace.Path,
+		}}
+	}
+	for _, p := range c.Workspace.Paths {
+		name := p.Name
+		if name == "" {
+			name = filepath.Base(p.Path)
+		}
+		roots = append(roots, workspace.Root{Name: name, Path: p.Path})
+	}
+
+	if tmp := c.TmpRoot(); isDir(tmp.Path) {
+		roots = append(roots, tmp)
+	}
+	return roots
+}
+
+// TmpRoot returns the root temporary tries are created in, preferably on
+// the tmpfs of $XDG_RUNTIME_DIR.
+func (c *Config) TmpRoot() workspace.Root {
+	path := ExpandHome(c.Tmp.Dir)
+	if path == "" {
+		if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+			path = filepath.Join(runtime, "gotry")
+		} else {
+			path = filepath.Join(os.TempDir(), fmt.Sprintf("gotry-%d", os.Getuid()))
+		}
+	}
+	return workspace.Root{Name: "tmp", Path: path}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// DefaultRoot returns the root new tries are created in.
+func (c *Config) DefaultRoot() (workspace.Root, error) {
+	r, err := c.root(c.Workspace.Default)
+	if err != nil {
+		return r, fmt.Errorf("workspace.default: %w", err)
+	}
+	return r, nil
+}
+
+// CloneRoot returns the root cloned repositories are placed in.
+func (c *Config) CloneRoot() (workspace.Root, error) {
+	if c.Workspace.CloneRoot == "" {
+		return c.DefaultRoot()
+	}
+	r, err := c.root(c.Workspace.CloneRoot)
+	if err != nil {
+		return r, fmt.Errorf("workspace.clone_root: %w", err)
+	}
+	return r, nil
+}
+
+// root returns the root called name, or the first root when name is
+// empty.
+func (c *Config) root(name string) (workspace.Root, error) {
+	roots := c.Roots()
+	if name == "" {
+		return roots[0], nil
+	}
+	for _, r := range roots {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return workspace.Root{}, fmt.Errorf("no workspace root named %q", name)
+}
+
+// SetPath replaces all configured roots with a single workspace path.
+func (c *Config) SetPath(path string) {
+	c.Workspace.Path = path
+	c.Workspace.Paths = nil
+	c.Workspace.Default = ""
+	c.Workspace.CloneRoot = ""
+}
+
 func (c *Config) EnsureWorkspaceExists() error {
-	return os.MkdirAll(c.Workspace.Path, 0755)
+	for _, r := range c.Roots() {
+		if err := os.MkdirAll(r.Path, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
 }
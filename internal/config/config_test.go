@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/spf13/viper"
+)
+
+// load reads config as the config file of a fresh home directory.
+func load(t *testing.T, config, profile string) (*Config, string, error) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(workspace.RealHomeEnv, "")
+	t.Setenv(ProfileEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(home, "run")) // no temporary root
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	dir := filepath.Join(home, ".config", "gotry")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(profile)
+	return cfg, home, err
+}
+
+const multiRoot = `
+[workspace]
+default = "tries"
+clone_root = "tries"
+
+[[workspace.paths]]
+name = "tries"
+path = "~/tries"
+
+[[workspace.paths]]
+name = "scratch"
+path = "~/scratch"
+`
+
+func TestProfileRoots(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    []string // root paths below home
+		def     string
+	}{
+		{"no profile", "", []string{"tries", "scratch"}, "tries"},
+		{"profile path", `
+[profiles.work.workspace]
+path = "~/worktries"
+`, []string{"worktries"}, "worktries"},
+		{"profile paths", `
+[profiles.work.workspace]
+default = "b"
+clone_root = "a"
+
+[[profiles.work.workspace.paths]]
+name = "a"
+path = "~/a"
+
+[[profiles.work.workspace.paths]]
+name = "b"
+path = "~/b"
+`, []string{"a", "b"}, "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := ""
+			if tt.profile != "" {
+				profile = "work"
+			}
+			cfg, home, err := load(t, multiRoot+tt.profile, profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range cfg.Roots() {
+				rel, _ := filepath.Rel(home, r.Path)
+				got = append(got, rel)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("roots %v, want %v", got, tt.want)
+			}
+			def, err := cfg.DefaultRoot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rel, _ := filepath.Rel(home, def.Path); rel != tt.def {
+				t.Errorf("default root %s, want %s", rel, tt.def)
+			}
+		})
+	}
+}
+
+func TestUnknownRoot(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"default", `
+[workspace]
+default = "nope"
+path = "~/tries"
+`, "workspace.default"},
+		{"clone root", strings.Replace(multiRoot, `clone_root = "tries"`, `clone_root = "nope"`, 1), "workspace.clone_root"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := load(t, tt.config, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one about %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPathDropsRootNames(t *testing.T) {
+	cfg, home, err := load(t, strings.Replace(multiRoot, `clone_root = "tries"`, `clone_root = "scratch"`, 1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetPath(filepath.Join(home, "other"))
+	for _, get := range []func() (workspace.Root, error){cfg.DefaultRoot, cfg.CloneRoot} {
+		r, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Path != filepath.Join(home, "other") {
+			t.Errorf("got root %s, want the --path directory", r.Path)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,10 +16,13 @@ const commitMessage = `✨ Let's try something new
 
 🤖 Created with gotry (https://github.com/raiden076/gotry)`
 
+// Init runs git init in path. Like every git command gotry runs while making
+// a try, it writes to stderr, since stdout carries the path the shell
+// integration changes into.
 func Init(path string) error {
 	cmd := exec.Command("git", "init")
 	cmd.Dir = path
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
@@ -40,14 +44,247 @@ func InitialCommit(path string) error {
 	// git commit
 	commitCmd := exec.Command("git", "commit", "-m", commitMessage)
 	commitCmd.Dir = path
-	commitCmd.Stdout = os.Stdout
+	commitCmd.Stdout = os.Stderr
 	commitCmd.Stderr = os.Stderr
 	return commitCmd.Run()
 }
 
+// OriginURL returns the URL of the origin remote, or "" if there is none.
+func OriginURL(path string) string {
+	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// IsRepo reports whether path is the top of a git work tree.
+func IsRepo(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil
+}
+
+// IsDirty reports whether the work tree at path has uncommitted changes or
+// untracked files.
+func IsDirty(path string) (bool, error) {
+	cmd := exec.Command("git", "--no-optional-locks", "status", "--porcelain")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return false, err
+	}
+	return len(strings.TrimSpace(string(out))) > 0, nil
+}
+
+// Status summarises the state of a work tree.
+type Status struct {
+	Branch     string // empty when HEAD is detached
+	Dirty      bool
+	Ahead      int
+	Behind     int
+	LastCommit time.Time // zero before the first commit
+}
+
+// GetStatus reads the branch, dirty state and upstream divergence of the
+// repository at path, along with the time of its last commit.
+//
+// Neither command refreshes the index, which would take index.lock and
+// make the user's own git commands fail while the selector reads statuses.
+func GetStatus(path string) (Status, error) {
+	cmd := exec.Command("git", "--no-optional-locks", "status", "--porcelain=v2", "--branch")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				s.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &s.Ahead, &s.Behind)
+		case line != "" && !strings.HasPrefix(line, "#"):
+			s.Dirty = true
+		}
+	}
+
+	// Quietly, as this runs behind the selector and fails on empty repos
+	log := exec.Command("git", "--no-optional-locks", "log", "-1", "--format=%ct")
+	log.Dir = path
+	if ct, err := log.Output(); err == nil {
+		if secs, err := strconv.ParseInt(strings.TrimSpace(string(ct)), 10, 64); err == nil {
+			s.LastCommit = time.Unix(secs, 0)
+		}
+	}
+	return s, nil
+}
+
+// RepairWorktrees re-links a moved repository or linked worktree with its
+// counterparts. It does nothing for repositories without worktrees.
+func RepairWorktrees(path string) error {
+	info, err := os.Stat(filepath.Join(path, ".git"))
+	if err != nil {
+		return nil
+	}
+	// From a main repository, name the linked worktrees so their .git
+	// files get pointed back at the new location too
+	args := []string{"worktree", "repair"}
+	if info.IsDir() {
+		entries, err := os.ReadDir(filepath.Join(path, ".git", "worktrees"))
+		if err != nil {
+			return nil
+		}
+		for _, e := range entries {
+			gitdir, err := os.ReadFile(filepath.Join(path, ".git", "worktrees", e.Name(), "gitdir"))
+			if err == nil {
+				args = append(args, filepath.Dir(strings.TrimSpace(string(gitdir))))
+			}
+		}
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = path
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// FirstCommitTime returns the committer date of the oldest commit
+// reachable from HEAD.
+func FirstCommitTime(path string) (time.Time, error) {
+	out, err := output(path, "log", "--reverse", "--format=%ct")
+	if err != nil {
+		return time.Time{}, err
+	}
+	first, _, _ := strings.Cut(out, "\n")
+	secs, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("no commits in %s", path)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// SetRemote points the named remote at url, adding it if needed.
+func SetRemote(path, name, url string) error {
+	verb := "add"
+	check := exec.Command("git", "remote", "get-url", name)
+	check.Dir = path
+	if check.Run() == nil {
+		verb = "set-url"
+	}
+
+	cmd := exec.Command("git", "remote", verb, name, url)
+	cmd.Dir = path
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// RewriteInitialCommit replaces the message of the root commit when it is
+// the one gotry created, replaying later commits on top. It reports whether
+// anything was rewritten.
+func RewriteInitialCommit(path, message string) (bool, error) {
+	root, err := output(path, "rev-list", "--max-parents=0", "HEAD")
+	if err != nil || strings.Contains(root, "\n") {
+		return false, nil // no commits, or several roots
+	}
+
+	body, err := output(path, "log", "-1", "--format=%B", root)
+	if err != nil || body != commitMessage {
+		return false, err
+	}
+
+	if dirty, err := IsDirty(path); err != nil || dirty {
+		return false, fmt.Errorf("commit or stash changes before rewriting history")
+	}
+
+	newRoot, err := output(path, "commit-tree", root+"^{tree}", "-m", message)
+	if err != nil {
+		return false, err
+	}
+
+	head, err := output(path, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	if head == root {
+		_, err = output(path, "reset", "--hard", newRoot)
+	} else {
+		_, err = output(path, "rebase", "--onto", newRoot, root)
+	}
+	return err == nil, err
+}
+
+// TrackedFiles lists the files tracked in the repository at path, relative
+// to path and slash-separated. Submodules are listed as their folder.
+func TrackedFiles(path string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z")
+	cmd.Dir = path
+	out, err := cmd.Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+			return nil, fmt.Errorf("git ls-files: %s", strings.TrimSpace(string(exit.Stderr)))
+		}
+		return nil, err
+	}
+	var files []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// output runs a git command in path and returns its trimmed stdout.
+func output(path string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = path
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// CloneLocal clones a repository on the same machine, hardlinking its
+// objects, into destPath (which may be an existing empty directory). The
+// new repository has all of the source's branches and keeps its origin
+// remote, with the source's view of it, rather than pointing at the source.
+func CloneLocal(srcPath, destPath string) error {
+	cmd := exec.Command("git", "clone", "--local", "--quiet", srcPath, destPath)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	// A clone only checks out the source's HEAD and sees its other
+	// branches as remote ones; make them local and replace the remote
+	// branches with the source's own
+	_, err := output(destPath, "fetch", "--quiet", "--prune", "--update-head-ok", srcPath,
+		"+refs/heads/*:refs/heads/*", "+refs/remotes/*:refs/remotes/*", "+refs/tags/*:refs/tags/*")
+	if err != nil {
+		return err
+	}
+
+	if origin := OriginURL(srcPath); origin != "" {
+		return SetRemote(destPath, "origin", origin)
+	}
+	_, err = output(destPath, "remote", "remove", "origin")
+	return err
+}
+
+// CheckoutNewBranch creates and switches to a new branch.
+func CheckoutNewBranch(path, branch string) error {
+	_, err := output(path, "checkout", "--quiet", "-b", branch)
+	return err
+}
+
 func Clone(repoURL, destPath string) error {
 	cmd := exec.Command("git", "clone", repoURL, destPath)
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = os.Stderr
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
@@ -90,11 +327,6 @@ func ParseGitURL(rawURL string) (*RepoInfo, error) {
 	}, nil
 }
 
-func (r *RepoInfo) DirectoryName() string {
-	today := time.Now().Format("2006-01-02")
-	return fmt.Sprintf("%s-%s-%s", today, r.User, r.Repo)
-}
-
 func IsGitURL(s string) bool {
 	return strings.HasPrefix(s, "git@") ||
 		strings.HasPrefix(s, "https://github.com") ||
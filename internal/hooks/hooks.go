@@ -0,0 +1,32 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Run executes each command through the system shell with dir as the
+// working directory. Output goes to stderr so stdout stays reserved for the
+// path consumed by the shell integration.
+func Run(commands []string, dir string, env ...string) error {
+	for _, command := range commands {
+		cmd := shellCommand(command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("hook %q: %w", command, err)
+		}
+	}
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,211 @@
+// Package mux opens tries in terminal multiplexer sessions.
+package mux
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	Tmux   = "tmux"
+	Zellij = "zellij"
+)
+
+// Mux opens each try in a session, or with Windows in a tmux window of the
+// current session, named after it.
+type Mux struct {
+	Kind    string // Tmux or Zellij
+	Windows bool
+	Layout  string // tmux command file or zellij layout, for new sessions
+	Socket  string // tmux server socket name, as for tmux -L
+}
+
+// New returns the multiplexer for kind, or nil when kind is empty.
+func New(kind, mode, layout, socket string) (*Mux, error) {
+	switch kind {
+	case "":
+		return nil, nil
+	case Tmux, Zellij:
+	default:
+		return nil, fmt.Errorf("unknown multiplexer %q (supported: tmux, zellij)", kind)
+	}
+	switch mode {
+	case "", "session":
+	case "window":
+		if kind != Tmux {
+			return nil, fmt.Errorf("window mode is only supported with tmux")
+		}
+	default:
+		return nil, fmt.Errorf("unknown multiplexer mode %q (supported: session, window)", mode)
+	}
+	return &Mux{Kind: kind, Windows: mode == "window", Layout: layout, Socket: socket}, nil
+}
+
+// Name returns the session or window name of a try. tmux reserves . and :
+// in target names.
+func Name(try string) string {
+	return strings.NewReplacer(".", "_", ":", "_").Replace(try)
+}
+
+// Open creates the session or window of a try if it is not running and
+// switches to it. It reports false when the multiplexer cannot switch
+// from where gotry runs, leaving the caller to fall back to changing
+// directory.
+func (m *Mux) Open(try, path string) (bool, error) {
+	if m.Kind == Zellij {
+		return m.openZellij(Name(try), path)
+	}
+	return m.openTmux(Name(try), path)
+}
+
+// Kill ends the session or windows of a try, if any. A nil Mux does
+// nothing.
+func (m *Mux) Kill(try string) error {
+	if m == nil {
+		return nil
+	}
+	name := Name(try)
+	if m.Kind == Zellij {
+		if !m.zellijRunning(name) {
+			return nil
+		}
+		return run(exec.Command("zellij", "delete-session", "--force", name))
+	}
+
+	if !m.Windows {
+		if m.tmux("has-session", "-t", "="+name).Run() != nil {
+			return nil
+		}
+		return run(m.tmux("kill-session", "-t", "="+name))
+	}
+	for _, target := range m.tmuxWindows(name) {
+		if err := run(m.tmux("kill-window", "-t", target)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mux) tmux(args ...string) *exec.Cmd {
+	if m.Socket != "" {
+		args = append([]string{"-L", m.Socket}, args...)
+	}
+	return exec.Command("tmux", args...)
+}
+
+// insideTmux reports whether gotry runs in a pane of the server it talks
+// to.
+func (m *Mux) insideTmux() bool {
+	if os.Getenv("TMUX") == "" {
+		return false
+	}
+	if m.Socket == "" {
+		return true
+	}
+	// $TMUX starts with the server's socket path
+	out, err := m.tmux("display-message", "-p", "#{socket_path}").Output()
+	return err == nil && strings.HasPrefix(os.Getenv("TMUX"), strings.TrimSpace(string(out))+",")
+}
+
+func (m *Mux) openTmux(name, path string) (bool, error) {
+	inside := m.insideTmux()
+	if m.Windows {
+		if !inside {
+			return false, nil
+		}
+		if windows := m.tmuxWindows(name); len(windows) > 0 {
+			return true, run(m.tmux("select-window", "-t", windows[0]))
+		}
+		return true, m.tmuxCreate(path, "new-window", "-n", name, "-c", path)
+	}
+
+	if m.tmux("has-session", "-t", "="+name).Run() != nil {
+		if err := m.tmuxCreate(path, "new-session", "-d", "-s", name, "-c", path); err != nil {
+			return false, err
+		}
+	}
+	if inside {
+		return true, run(m.tmux("switch-client", "-t", "="+name))
+	}
+	return true, attach(m.tmux("attach-session", "-t", "="+name))
+}
+
+// tmuxCreate runs a command making a session or window, followed by the
+// layout file, whose commands then act on the new session or window.
+func (m *Mux) tmuxCreate(path string, create ...string) error {
+	args := append(create, "-e", "GOTRY_PATH="+path)
+	if m.Layout != "" {
+		args = append(args, ";", "source-file", m.Layout)
+	}
+	return run(m.tmux(args...))
+}
+
+// tmuxWindows returns the targets of the windows named name in any
+// session.
+func (m *Mux) tmuxWindows(name string) []string {
+	out, err := m.tmux("list-windows", "-a", "-F", "#{window_id} #{window_name}").Output()
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if id, window, ok := strings.Cut(line, " "); ok && window == name {
+			targets = append(targets, id)
+		}
+	}
+	return targets
+}
+
+func (m *Mux) openZellij(name, path string) (bool, error) {
+	// zellij cannot switch sessions from the command line
+	if os.Getenv("ZELLIJ") != "" {
+		return false, nil
+	}
+	var cmd *exec.Cmd
+	if m.zellijRunning(name) || m.Layout == "" {
+		cmd = exec.Command("zellij", "attach", "--create", name)
+	} else {
+		cmd = exec.Command("zellij", "--session", name, "--layout", m.Layout)
+	}
+	cmd.Dir = path
+	cmd.Env = append(os.Environ(), "GOTRY_PATH="+path)
+	return true, attach(cmd)
+}
+
+func (m *Mux) zellijRunning(name string) bool {
+	out, err := exec.Command("zellij", "list-sessions", "--short", "--no-formatting").Output()
+	if err != nil {
+		return false
+	}
+	for _, session := range strings.Fields(string(out)) {
+		if session == name {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs a command, turning its error output into the error.
+func run(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
+
+// attach runs a client on the terminal. stdout is the terminal too, since
+// the shell integration captures gotry's own stdout.
+func attach(cmd *exec.Cmd) error {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
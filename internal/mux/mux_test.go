@@ -0,0 +1,112 @@
+package mux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// testTmux starts a private tmux server with one session and makes gotry
+// look as if it runs in a pane of it. The server and its socket go away
+// when the test ends.
+func testTmux(t *testing.T, windows bool) *Mux {
+	t.Helper()
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	m := &Mux{Kind: Tmux, Windows: windows, Socket: fmt.Sprintf("gotry-test-%d", os.Getpid())}
+	t.Cleanup(func() { m.tmux("kill-server").Run() })
+
+	if err := run(m.tmux("-f", "/dev/null", "new-session", "-d", "-s", "main")); err != nil {
+		t.Fatal(err)
+	}
+	out, err := m.tmux("display-message", "-p", "-t", "=main", "#{socket_path},#{pid},#{session_id}").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMUX", strings.Replace(strings.TrimSpace(string(out)), "$", "", 1))
+	if !m.insideTmux() {
+		t.Fatal("not inside the test server")
+	}
+	return m
+}
+
+func (m *Mux) sessionRunning(name string) bool {
+	return m.tmux("has-session", "-t", "="+name).Run() == nil
+}
+
+func TestTmuxSession(t *testing.T) {
+	m := testTmux(t, false)
+	dir := t.TempDir()
+	try := "2026-10-19-api.v2"
+	name := Name(try)
+
+	// No client is attached to switch, only the session is checked
+	ok, _ := m.Open(try, dir)
+	if !ok {
+		t.Fatal("Open reported it cannot switch from inside tmux")
+	}
+	if !m.sessionRunning(name) {
+		t.Fatalf("session %s was not created", name)
+	}
+	out, err := m.tmux("show-environment", "-t", "="+name, "GOTRY_PATH").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "GOTRY_PATH="+dir {
+		t.Errorf("session environment has %q, want GOTRY_PATH=%s", got, dir)
+	}
+
+	if err := m.Kill(try); err != nil {
+		t.Fatal(err)
+	}
+	if m.sessionRunning(name) {
+		t.Errorf("session %s is still running", name)
+	}
+	if !m.sessionRunning("main") {
+		t.Error("Kill ended another session")
+	}
+	if err := m.Kill(try); err != nil {
+		t.Errorf("Kill of a try without a session: %v", err)
+	}
+}
+
+func TestTmuxWindow(t *testing.T) {
+	m := testTmux(t, true)
+	dir := t.TempDir()
+	try := "2026-10-19-api.v2"
+	name := Name(try)
+
+	if windows := m.tmuxWindows(name); len(windows) != 0 {
+		t.Fatalf("windows %v before Open", windows)
+	}
+	for range 2 {
+		ok, err := m.Open(try, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatal("Open reported it cannot switch from inside tmux")
+		}
+	}
+	windows := m.tmuxWindows(name)
+	if len(windows) != 1 {
+		t.Fatalf("got windows %v, want one window named %s", windows, name)
+	}
+	if others := m.tmuxWindows(name + "x"); len(others) != 0 {
+		t.Errorf("windows %v match another name", others)
+	}
+
+	if err := m.Kill(try); err != nil {
+		t.Fatal(err)
+	}
+	if windows := m.tmuxWindows(name); len(windows) != 0 {
+		t.Errorf("windows %v left after Kill", windows)
+	}
+	if !m.sessionRunning("main") {
+		t.Error("Kill ended the session")
+	}
+}
@@ -0,0 +1,451 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+	"github.com/sahilm/fuzzy"
+)
+
+// Query is a parsed search expression: qualifiers such as age:<7d or
+// #tag narrow the set of tries, and the remaining free text is fuzzy
+// matched against their names.
+type Query struct {
+	Text  string
+	Tags  []string
+	Types []string // project types asked for with lang: or type:
+	terms []term
+}
+
+type term struct {
+	negate bool
+	match  func(workspace.Directory, *Facts) bool
+}
+
+// Parse splits input into qualifiers and free text. Words that look like
+// key:value but use an unknown key are kept as text.
+func Parse(input string) (*Query, error) {
+	q := &Query{}
+	var words []string
+
+	for _, word := range strings.Fields(input) {
+		negate := false
+		raw := word
+		if len(word) > 1 && (word[0] == '-' || word[0] == '!') {
+			negate = true
+			word = word[1:]
+		}
+
+		if strings.HasPrefix(word, "#") && len(word) > 1 {
+			tag := word[1:]
+			if negate {
+				q.terms = append(q.terms, term{negate: true, match: hasTag(tag)})
+			} else {
+				q.Tags = append(q.Tags, tag)
+			}
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(word, ":")
+		if !hasValue {
+			if word == "pinned" {
+				q.terms = append(q.terms, term{negate, func(d workspace.Directory, _ *Facts) bool {
+					return d.Meta.Pinned
+				}})
+				continue
+			}
+			words = append(words, raw)
+			continue
+		}
+
+		match, known, err := qualifier(strings.ToLower(key), value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", word, err)
+		}
+		if !known {
+			words = append(words, raw)
+			continue
+		}
+		q.terms = append(q.terms, term{negate, match})
+		if k := strings.ToLower(key); (k == "lang" || k == "type") && !negate {
+			q.Types = append(q.Types, strings.ToLower(value))
+		}
+	}
+
+	for _, tag := range q.Tags {
+		q.terms = append(q.terms, term{match: hasTag(tag)})
+	}
+
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+func hasTag(tag string) func(workspace.Directory, *Facts) bool {
+	return func(d workspace.Directory, _ *Facts) bool {
+		return d.Meta.HasTag(tag)
+	}
+}
+
+func qualifier(key, value string) (func(workspace.Directory, *Facts) bool, bool, error) {
+	switch key {
+	case "tag":
+		return hasTag(value), true, nil
+
+	case "age":
+		op, rest := splitOp(value, '<')
+		d, err := ParseDuration(rest)
+		if err != nil {
+			return nil, true, err
+		}
+		return func(dir workspace.Directory, _ *Facts) bool {
+			return compare(op, int64(time.Since(dir.LastUsed())), int64(d))
+		}, true, nil
+
+	case "created":
+		op, rest := splitOp(value, '=')
+		start, end, err := parseDateRange(rest)
+		if err != nil {
+			return nil, true, err
+		}
+		return func(dir workspace.Directory, _ *Facts) bool {
+			t := created(dir)
+			switch op {
+			case '<':
+				return t.Before(start)
+			case '>':
+				return !t.Before(end)
+			default:
+				return !t.Before(start) && t.Before(end)
+			}
+		}, true, nil
+
+	case "lang", "type":
+		return func(dir workspace.Directory, f *Facts) bool {
+			for _, t := range f.Types(dir.Path) {
+				if strings.EqualFold(t, value) {
+					return true
+				}
+			}
+			return false
+		}, true, nil
+
+	case "git":
+		switch value {
+		case "repo", "none":
+			want := value == "repo"
+			return func(dir workspace.Directory, _ *Facts) bool {
+				return git.IsRepo(dir.Path) == want
+			}, true, nil
+		case "dirty", "clean":
+			want := value == "dirty"
+			return func(dir workspace.Directory, f *Facts) bool {
+				dirty, ok := f.Dirty(dir.Path)
+				return ok && dirty == want
+			}, true, nil
+		}
+		return nil, true, fmt.Errorf("expected repo, none, dirty or clean")
+
+	case "origin":
+		value = strings.ToLower(value)
+		switch value {
+		case workspace.OriginNew, workspace.OriginClone, workspace.OriginFork, workspace.OriginAdopt:
+		default:
+			return nil, true, fmt.Errorf("expected %s, %s, %s or %s",
+				workspace.OriginNew, workspace.OriginClone, workspace.OriginFork, workspace.OriginAdopt)
+		}
+		return func(dir workspace.Directory, f *Facts) bool {
+			return f.Origin(dir) == value
+		}, true, nil
+
+	case "host":
+		return func(dir workspace.Directory, f *Facts) bool {
+			return strings.EqualFold(f.Host(dir.Path), value)
+		}, true, nil
+
+	case "size":
+		op, rest := splitOp(value, '>')
+		n, err := ParseSize(rest)
+		if err != nil {
+			return nil, true, err
+		}
+		return func(dir workspace.Directory, f *Facts) bool {
+			size, ok := f.Size(dir)
+			return ok && compare(op, size, n)
+		}, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// splitOp strips a leading comparison operator, returning def when absent.
+func splitOp(value string, def byte) (byte, string) {
+	if value != "" && (value[0] == '<' || value[0] == '>' || value[0] == '=') {
+		return value[0], value[1:]
+	}
+	return def, value
+}
+
+func compare(op byte, a, b int64) bool {
+	switch op {
+	case '<':
+		return a < b
+	case '>':
+		return a > b
+	default:
+		return a == b
+	}
+}
+
+func created(dir workspace.Directory) time.Time {
+	if !dir.Date.IsZero() {
+		return dir.Date
+	}
+	return dir.ModTime
+}
+
+// ParseDuration accepts a number followed by m, h, d, w or y.
+func ParseDuration(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+		'y': 365 * 24 * time.Hour,
+	}
+	unit, ok := units[s[len(s)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q (units: m, h, d, w, y)", s)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// parseDateRange turns 2025, 2025-11 or 2025-11-03 into the half-open
+// interval it covers.
+func parseDateRange(s string) (time.Time, time.Time, error) {
+	for _, f := range []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	} {
+		if t, err := time.ParseInLocation(f.layout, s, time.Local); err == nil {
+			return t, f.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q (use YYYY, YYYY-MM or YYYY-MM-DD)", s)
+}
+
+// ParseSize parses a byte count with an optional K, M, G or T suffix.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSuffix(strings.ToUpper(s), "B")
+	mult := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// Matches reports whether dir satisfies every qualifier, ignoring the free
+// text.
+func (q *Query) Matches(dir workspace.Directory, f *Facts) bool {
+	for _, t := range q.terms {
+		if t.match(dir, f) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter applies the qualifiers and then ranks the survivors by fuzzy
+// matching the free text against their names.
+func (q *Query) Filter(dirs []workspace.Directory, f *Facts) []workspace.Directory {
+	candidates := dirs
+	if len(q.terms) > 0 {
+		candidates = nil
+		for _, d := range dirs {
+			if q.Matches(d, f) {
+				candidates = append(candidates, d)
+			}
+		}
+	}
+
+	if q.Text == "" {
+		return candidates
+	}
+
+	matches := fuzzy.FindFrom(q.Text, searchable(candidates))
+	filtered := make([]workspace.Directory, len(matches))
+	for i, match := range matches {
+		filtered[i] = candidates[match.Index]
+	}
+	return filtered
+}
+
+type searchable []workspace.Directory
+
+func (s searchable) String(i int) string { return s[i].Name }
+func (s searchable) Len() int            { return len(s) }
+
+// Facts memoizes the expensive per-try lookups qualifiers need, so that
+// re-filtering on every keystroke only pays for them once.
+type Facts struct {
+	mu     sync.Mutex
+	size   map[string]int64
+	caches map[string]*workspace.SizeCache
+	dirty  map[string]*bool
+	types  map[string][]string
+	env    map[string]string
+	host   map[string]string
+
+	// Background is set by callers that gather sizes and git statuses
+	// themselves, like the selector, which cannot block on them while
+	// filtering. Size and Dirty then only report what is in Sizes and
+	// Status so far.
+	Background bool
+	Sizes      map[string]int64
+	Status     map[string]git.Status
+}
+
+func NewFacts() *Facts {
+	return &Facts{
+		size:   map[string]int64{},
+		caches: map[string]*workspace.SizeCache{},
+		dirty:  map[string]*bool{},
+		types:  map[string][]string{},
+		env:    map[string]string{},
+		host:   map[string]string{},
+	}
+}
+
+// Size returns the bytes held by a try, and false for ok while it is not
+// known.
+func (f *Facts) Size(dir workspace.Directory) (n int64, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Background {
+		n, ok = f.Sizes[dir.Path]
+		return n, ok
+	}
+	if n, ok := f.size[dir.Path]; ok {
+		return n, true
+	}
+	cache := f.caches[dir.RootPath]
+	if cache == nil {
+		cache = workspace.LoadSizeCache(dir.RootPath)
+		f.caches[dir.RootPath] = cache
+	}
+	n, err := cache.Size(dir.Path)
+	if err != nil {
+		return 0, false
+	}
+	f.size[dir.Path] = n
+	return n, true
+}
+
+// Dirty returns the git dirty state, and false for ok if path is not a
+// repository.
+func (f *Facts) Dirty(path string) (dirty, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Background {
+		st, ok := f.Status[path]
+		return st.Dirty, ok
+	}
+	if d, seen := f.dirty[path]; seen {
+		return d != nil && *d, d != nil
+	}
+	if !git.IsRepo(path) {
+		f.dirty[path] = nil
+		return false, false
+	}
+	d, err := git.IsDirty(path)
+	if err != nil {
+		f.dirty[path] = nil
+		return false, false
+	}
+	f.dirty[path] = &d
+	return d, true
+}
+
+func (f *Facts) Types(path string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t, ok := f.types[path]; ok {
+		return t
+	}
+	t := workspace.DetectTypes(path)
+	f.types[path] = t
+	return t
+}
+
+// Env returns the kind of environment a try defines, or "".
+func (f *Facts) Env(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, ok := f.env[path]; ok {
+		return e
+	}
+	e := workspace.DetectEnv(path)
+	f.env[path] = e
+	return e
+}
+
+// Host returns the host of the origin remote, or "".
+func (f *Facts) Host(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if h, ok := f.host[path]; ok {
+		return h
+	}
+	h := ""
+	if origin := git.OriginURL(path); origin != "" {
+		if info, err := git.ParseGitURL(origin); err == nil {
+			h = info.Host
+		}
+	}
+	f.host[path] = h
+	return h
+}
+
+// Origin reports whether a try was cloned or created, falling back to the
+// presence of a remote for tries made before origins were recorded.
+func (f *Facts) Origin(dir workspace.Directory) string {
+	if dir.Meta.Origin != "" {
+		return dir.Meta.Origin
+	}
+	if f.Host(dir.Path) != "" {
+		return workspace.OriginClone
+	}
+	return workspace.OriginNew
+}
@@ -0,0 +1,162 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
+)
+
+// fixture returns three tries and facts about them as the selector's
+// background scans would report them:
+//
+//	api    go, dated today, used an hour ago, pinned, #work, 2G, dirty
+//	web    node, dated 2026-01-05, used 30 days ago, #web, 10M, clean
+//	notes  no date, last used in 2020, not a repository
+func fixture(t *testing.T) ([]workspace.Directory, *Facts) {
+	t.Helper()
+	root := t.TempDir()
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	dirs := []workspace.Directory{
+		{Name: "api", Date: today, ModTime: now.Add(-time.Hour),
+			Meta: workspace.TryMeta{Tags: []string{"work"}, Pinned: true}},
+		{Name: "web", Date: time.Date(2026, 1, 5, 0, 0, 0, 0, time.Local), ModTime: now.AddDate(0, 0, -30),
+			Meta: workspace.TryMeta{Tags: []string{"web"}}},
+		{Name: "notes", ModTime: time.Date(2020, 6, 1, 12, 0, 0, 0, time.Local)},
+	}
+	for i := range dirs {
+		dirs[i].RootPath = root
+		dirs[i].Path = filepath.Join(root, dirs[i].Name)
+		if err := os.Mkdir(dirs[i].Path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, marker := range map[string]string{"api": "go.mod", "web": "package.json"} {
+		if err := os.WriteFile(filepath.Join(root, name, marker), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewFacts()
+	f.Background = true
+	f.Sizes = map[string]int64{dirs[0].Path: 2 << 30, dirs[1].Path: 10 << 20, dirs[2].Path: 4 << 10}
+	f.Status = map[string]git.Status{dirs[0].Path: {Dirty: true}, dirs[1].Path: {}}
+	return dirs, f
+}
+
+func TestFilter(t *testing.T) {
+	dirs, facts := fixture(t)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "api web notes"},
+		{"api", "api"},
+		{"nts", "notes"},
+		{"age:<7d", "api"},
+		{"age:7d", "api"},
+		{"age:>7d", "web notes"},
+		{"age:>1y", "notes"},
+		{"created:2026-01", "web"},
+		{"created:2026-01-05", "web"},
+		{"created:<2026", "notes"},
+		{"created:>2026-01-05", "api"},
+		{"lang:go", "api"},
+		{"type:NODE", "web"},
+		{"-lang:go", "web notes"},
+		{"git:dirty", "api"},
+		{"git:clean", "web"},
+		{"!git:dirty", "web notes"},
+		{"size:>1G", "api"},
+		{"size:>1m", "api web"},
+		{"size:<5K", "notes"},
+		{"pinned", "api"},
+		{"!pinned", "web notes"},
+		{"-pinned", "web notes"},
+		{"#work", "api"},
+		{"#WORK", "api"},
+		{"-#work", "web notes"},
+		{"tag:web", "web"},
+		{"lang:go age:<7d api", "api"},
+		{"lang:go web", ""},
+		{"foo:bar", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range q.Filter(dirs, facts) {
+				got = append(got, d.Name)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %q, want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		tags  []string
+		types []string
+	}{
+		{"redis cache", "redis cache", nil, nil},
+		{"#perf redis", "redis", []string{"perf"}, nil},
+		{"-#perf redis", "redis", nil, nil},
+		{"lang:go type:Rust api", "api", nil, []string{"go", "rust"}},
+		{"-lang:go api", "api", nil, nil},
+		{"foo:bar", "foo:bar", nil, nil},
+		{"- #", "- #", nil, nil},
+		{"!scratch", "!scratch", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := Parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Text != tt.text {
+				t.Errorf("text %q, want %q", q.Text, tt.text)
+			}
+			if !slices.Equal(q.Tags, tt.tags) {
+				t.Errorf("tags %q, want %q", q.Tags, tt.tags)
+			}
+			if !slices.Equal(q.Types, tt.types) {
+				t.Errorf("types %q, want %q", q.Types, tt.types)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, query := range []string{
+		"age:7x",
+		"age:",
+		"age:<d",
+		"created:2026-13",
+		"created:yesterday",
+		"size:>lots",
+		"size:",
+		"git:maybe",
+		"origin:stolen",
+		"api size:>1Q",
+	} {
+		if q, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", query, q)
+		}
+	}
+}
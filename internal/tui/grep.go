@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/raiden076/gotry/internal/workspace"
+)
+
+const (
+	grepDelay      = 200 * time.Millisecond
+	grepMaxHits    = 200
+	grepHitsPerTry = 20
+)
+
+// grepTickMsg fires once typing pauses; searches for stale input are
+// dropped by comparing generations.
+type grepTickMsg struct {
+	gen int
+}
+
+// contentIndexMsg carries the content indexes of the roots being searched,
+// which lets searches skip files that cannot match.
+type contentIndexMsg struct {
+	content map[string]*workspace.ContentIndex
+}
+
+type grepDoneMsg struct {
+	gen  int
+	hits []workspace.Hit
+	err  error
+}
+
+// enterGrep switches the search box to searching file contents of the
+// tries currently listed.
+func (m Model) enterGrep() (tea.Model, tea.Cmd) {
+	m.mode = ModeGrep
+	m.grepDirs = m.filtered
+	m.savedQuery = m.searchInput.Value()
+	m.searchInput.SetValue("")
+	m.searchInput.Placeholder = "Search file contents (regexp)..."
+	m.grepHits = nil
+	m.grepErr = nil
+	m.cursor = 0
+	dirs := m.grepDirs
+	return m, func() tea.Msg {
+		return contentIndexMsg{workspace.LoadContentIndexes(dirs)}
+	}
+}
+
+func (m Model) leaveGrep() (tea.Model, tea.Cmd) {
+	m.mode = ModeNormal
+	m.grepGen++
+	m.searchInput.SetValue(m.savedQuery)
+	m.searchInput.Placeholder = "Search or create..."
+	m.filterDirectories()
+	m.cursor = 0
+	return m, nil
+}
+
+func (m Model) handleGrepMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc", "ctrl+g":
+		return m.leaveGrep()
+
+	case "enter":
+		if m.cursor < len(m.grepHits) {
+			hit := m.grepHits[m.cursor]
+			m.selected = hit.Dir.Path
+			m.selectedDir = hit.Dir
+			m.selectedFile = hit.File
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case "up", "ctrl+p":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+
+	case "down", "ctrl+n":
+		if m.cursor < len(m.grepHits)-1 {
+			m.cursor++
+		}
+		return m, nil
+	}
+
+	before := m.searchInput.Value()
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() == before {
+		return m, cmd
+	}
+
+	m.grepGen++
+	gen := m.grepGen
+	return m, tea.Batch(cmd, tea.Tick(grepDelay, func(time.Time) tea.Msg {
+		return grepTickMsg{gen}
+	}))
+}
+
+// runGrep searches the tries in the background.
+func (m Model) runGrep() tea.Cmd {
+	gen, pattern, dirs, skip, content := m.grepGen, m.searchInput.Value(), m.grepDirs, m.grepSkip, m.grepContent
+	return func() tea.Msg {
+		if pattern == "" {
+			return grepDoneMsg{gen: gen}
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return grepDoneMsg{gen: gen, err: err}
+		}
+
+		var hits []workspace.Hit
+		opts := workspace.GrepOptions{Skip: skip, Limit: grepHitsPerTry, Content: content}
+		workspace.Grep(dirs, re, opts, func(found []workspace.Hit) bool {
+			hits = append(hits, found...)
+			return len(hits) < grepMaxHits
+		})
+		return grepDoneMsg{gen: gen, hits: hits}
+	}
+}
+
+func (m Model) renderGrep() string {
+	var b strings.Builder
+	switch {
+	case m.grepErr != nil:
+		b.WriteString(markedStyle.Render("  " + m.grepErr.Error()))
+		b.WriteString("\n")
+	case m.searchInput.Value() == "":
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  Type to search the files of %d tries.", len(m.grepDirs))))
+		b.WriteString("\n")
+	case len(m.grepHits) == 0:
+		b.WriteString(dimStyle.Render("  No matches."))
+		b.WriteString("\n")
+	}
+
+	start, end := m.visibleRange(len(m.grepHits), 2)
+	for i := start; i < end; i++ {
+		hit := m.grepHits[i]
+		name := normalStyle.Render(hit.Dir.Name)
+		prefix := "   "
+		if i == m.cursor {
+			name = selectedStyle.Render(hit.Dir.Name)
+			prefix = selectedStyle.Render(" → ")
+		}
+		b.WriteString(prefix)
+		b.WriteString(name)
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  %s:%d", hit.Rel, hit.Line)))
+		b.WriteString("\n      ")
+		b.WriteString(dimStyle.Render(truncate(hit.Text, 100)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
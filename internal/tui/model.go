@@ -1,10 +1,22 @@
 package tui
 
 import (
-	"github.com/raiden076/gotry/internal/workspace"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aymanbagabas/go-osc52/v2"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/sahilm/fuzzy"
+	"github.com/fsnotify/fsnotify"
+	"github.com/raiden076/gotry/internal/config"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/mux"
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
 )
 
 type Mode int
@@ -13,29 +25,74 @@ const (
 	ModeNormal Mode = iota
 	ModeDelete
 	ModeConfirm
+	ModeTags
+	ModeRename
+	ModeFork
+	ModeGrep
 )
 
 type Model struct {
 	// Config
-	basePath string
+	roots      []workspace.Root
+	createRoot int // index into roots for new tries
+	naming     *workspace.Naming
+	profile    string
+	ascii      bool // plain-text icons
+	watcher    *fsnotify.Watcher
 
 	// State
 	directories []workspace.Directory
 	filtered    []workspace.Directory
 	cursor      int
 	mode        Mode
-	marked      map[int]bool // indices marked for deletion
+	marked      map[string]workspace.Directory // tries marked for deletion, by path
+	target      workspace.Directory            // try the open prompt acts on
 	confirmText string
+	facts       *query.Facts
+	queryErr    error
+	sizes       map[string]int64 // nil until the scan finishes
+	sortBySize  bool
+	gitStatus   map[string]git.Status
+	notes       map[string]workspace.Note // latest note of each try
+	editor      []string
+	copyCmd     []string // nil copies with OSC52
+	mux         *mux.Mux // sessions to end with deleted tries
+	tmpRoot     workspace.Root
+	flash       string // result of the last action, until the next key
+	height      int    // terminal rows, 0 until known
+	reloadGen   int
+
+	// Content search
+	grepDirs    []workspace.Directory
+	grepSkip    []string
+	grepContent map[string]*workspace.ContentIndex
+	grepHits    []workspace.Hit
+	grepGen     int
+	grepErr     error
+	savedQuery  string // search box contents before grep mode
 
 	// Components
 	searchInput textinput.Model
+	promptInput textinput.Model // tag editing, renaming and forking
 
 	// Output
-	selected string
-	quitting bool
+	selected     string
+	selectedDir  workspace.Directory
+	selectedFile string
+	quitting     bool
 }
 
-func NewModel(basePath string, initialQuery string) Model {
+func NewModel(cfg *config.Config, initialQuery string) Model {
+	roots := cfg.Roots()
+	// Load has checked the default root exists
+	def, _ := cfg.DefaultRoot()
+	createRoot := 0
+	for i, r := range roots {
+		if r == def {
+			createRoot = i
+		}
+	}
+
 	ti := textinput.New()
 	ti.Placeholder = "Search or create..."
 	ti.Focus()
@@ -43,22 +100,89 @@ func NewModel(basePath string, initialQuery string) Model {
 	ti.Width = 40
 	ti.SetValue(initialQuery)
 
-	return Model{
-		basePath:    basePath,
+	pi := textinput.New()
+	pi.CharLimit = 200
+	pi.Width = 40
+
+	// Watch the roots so tries created or removed elsewhere show up while
+	// the selector is open
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		for _, r := range roots {
+			watcher.Add(r.Path)
+		}
+	} else {
+		watcher = nil
+	}
+
+	// A misconfigured multiplexer is reported when a try is opened
+	mx, _ := cfg.Mux()
+
+	m := Model{
+		mux:         mx,
+		tmpRoot:     cfg.TmpRoot(),
+		watcher:     watcher,
+		roots:       roots,
+		createRoot:  createRoot,
+		naming:      cfg.Naming(),
+		profile:     cfg.Profile,
+		ascii:       asciiIcons(cfg.UI.Icons),
+		grepSkip:    cfg.ArtifactNames(),
 		searchInput: ti,
-		marked:      make(map[int]bool),
+		promptInput: pi,
+		marked:      make(map[string]workspace.Directory),
+		gitStatus:   make(map[string]git.Status),
+		notes:       make(map[string]workspace.Note),
+		editor:      cfg.Editor(),
+		copyCmd:     cfg.CopyCommand(),
+	}
+	m.facts = m.newFacts()
+	return m
+}
+
+// newFacts starts the per-try lookups over for a fresh listing. Sizes and
+// git statuses come from the background scans, so filtering never waits
+// for them.
+func (m Model) newFacts() *query.Facts {
+	f := query.NewFacts()
+	f.Background = true
+	f.Sizes = m.sizes
+	f.Status = m.gitStatus
+	return f
+}
+
+// asciiIcons decides between emoji and plain-text icons. In auto mode,
+// emoji are used unless the terminal is a bare console or the locale is
+// not UTF-8.
+func asciiIcons(setting string) bool {
+	switch setting {
+	case "ascii":
+		return true
+	case "emoji":
+		return false
+	}
+	if term := os.Getenv("TERM"); term == "linux" || term == "dumb" {
+		return true
+	}
+	for _, v := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(v); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
 	}
+	return false
 }
 
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		textinput.Blink,
 		m.loadDirectories,
+		watchRoots(m.watcher),
 	)
 }
 
 func (m Model) loadDirectories() tea.Msg {
-	dirs, err := workspace.List(m.basePath)
+	dirs, err := workspace.ListRoots(m.roots, m.naming)
 	if err != nil {
 		return errMsg{err}
 	}
@@ -70,41 +194,273 @@ type dirsLoadedMsg struct {
 	dirs []workspace.Directory
 }
 
-type errMsg struct {
-	err error
+type sizesMsg struct {
+	sizes map[string]int64
+}
+
+func scanSizes(dirs []workspace.Directory) tea.Cmd {
+	return func() tea.Msg {
+		return sizesMsg{workspace.ScanSizes(dirs)}
+	}
 }
 
-// Fuzzy search implementation
-type dirSearchable []workspace.Directory
+// notesMsg carries the latest note of the tries that have notes.
+type notesMsg struct {
+	notes map[string]workspace.Note
+}
 
-func (d dirSearchable) String(i int) string {
-	return d[i].Name
+func readNotes(dirs []workspace.Directory) tea.Cmd {
+	return func() tea.Msg {
+		notes := make(map[string]workspace.Note)
+		for _, d := range dirs {
+			if d.Archived() {
+				continue
+			}
+			if n, ok := workspace.LatestNote(d); ok {
+				notes[d.Path] = n
+			}
+		}
+		return notesMsg{notes}
+	}
 }
 
-func (d dirSearchable) Len() int {
-	return len(d)
+// editNotes opens the notes of a try in the editor under a fresh heading,
+// suspending the selector until the editor exits.
+func (m Model) editNotes(dir workspace.Directory) tea.Cmd {
+	path, err := workspace.StartNote(dir, "", time.Now())
+	if err != nil {
+		return func() tea.Msg { return errMsg{err} }
+	}
+	editor := config.WithPath(m.editor, path)
+	c := exec.Command(editor[0], editor[1:]...)
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err != nil {
+			return errMsg{err}
+		}
+		return readNotes([]workspace.Directory{dir})()
+	})
 }
 
-func (m *Model) filterDirectories() {
-	query := m.searchInput.Value()
+type copiedMsg struct {
+	path string
+	err  error
+}
+
+// copyPath puts a path on the clipboard, through actions.copy or else an
+// OSC52 escape sequence the terminal (and tmux or screen in between)
+// turns into a clipboard update.
+func (m Model) copyPath(path string) tea.Cmd {
+	command := m.copyCmd
+	return func() tea.Msg {
+		if len(command) > 0 {
+			c := exec.Command(command[0], command[1:]...)
+			c.Stdin = strings.NewReader(path)
+			return copiedMsg{path, c.Run()}
+		}
+		seq := osc52.New(path)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+			seq = seq.Screen()
+		}
+		_, err := seq.WriteTo(os.Stderr)
+		return copiedMsg{path, err}
+	}
+}
+
+// gitStatusMsg carries the status of one repository. Statuses are read by
+// a small pool of workers and arrive one by one, so the list is usable
+// before every repository has been inspected.
+type gitStatusMsg struct {
+	path   string
+	status git.Status
+	next   <-chan gitStatusMsg
+}
+
+const gitStatusWorkers = 8
+
+func readGitStatus(dirs []workspace.Directory) tea.Cmd {
+	jobs := make(chan string)
+	results := make(chan gitStatusMsg)
+
+	var wg sync.WaitGroup
+	for range gitStatusWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				if status, err := git.GetStatus(path); err == nil {
+					results <- gitStatusMsg{path: path, status: status, next: results}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, d := range dirs {
+			if d.Archived() || !git.IsRepo(d.Path) {
+				continue
+			}
+			jobs <- d.Path
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	return waitGitStatus(results)
+}
+
+type gitStatusDoneMsg struct{}
+
+func waitGitStatus(results <-chan gitStatusMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-results
+		if !ok {
+			return gitStatusDoneMsg{}
+		}
+		return msg
+	}
+}
 
-	if query == "" {
-		m.filtered = m.directories
+// saveGitStatus keeps the statuses in the index for the next launch.
+func saveGitStatus(dirs []workspace.Directory, statuses map[string]git.Status) tea.Cmd {
+	return func() tea.Msg {
+		workspace.SaveGitStatus(dirs, statuses)
+		return nil
+	}
+}
+
+// rootsChangedMsg reports a try appearing or disappearing in a root.
+type rootsChangedMsg struct{}
+
+// reloadMsg fires once changes to the roots have settled.
+type reloadMsg struct {
+	gen int
+}
+
+const reloadDelay = 300 * time.Millisecond
+
+func watchRoots(w *fsnotify.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return nil
+				}
+				// The index, caches and metadata live in dot folders
+				if strings.HasPrefix(filepath.Base(ev.Name), ".") || ev.Op == fsnotify.Chmod {
+					continue
+				}
+				return rootsChangedMsg{}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+type errMsg struct {
+	err error
+}
+
+func (m *Model) filterDirectories() {
+	q, err := query.Parse(m.searchInput.Value())
+	m.queryErr = err
+	if err != nil {
+		m.filtered = nil
 		return
 	}
 
-	matches := fuzzy.FindFrom(query, dirSearchable(m.directories))
+	m.filtered = q.Filter(m.directories, m.facts)
+	if m.sortBySize && m.sizes != nil {
+		// Filter may hand back the full list, which stays in recency order
+		m.filtered = append([]workspace.Directory(nil), m.filtered...)
+		workspace.SortBySize(m.filtered, m.sizes)
+	}
+}
 
-	m.filtered = make([]workspace.Directory, len(matches))
-	for i, match := range matches {
-		m.filtered[i] = m.directories[match.Index]
+// refresh re-applies the search after the listing changed. Outside normal
+// mode it waits, so marks and prompts keep pointing at the tries under
+// the cursor; leaving those modes refreshes.
+func (m *Model) refresh() {
+	if m.mode != ModeNormal {
+		return
+	}
+	m.filterDirectories()
+	if m.cursor >= len(m.filtered) {
+		m.cursor = max(0, len(m.filtered)-1)
 	}
 }
 
+// parseQuery returns the parsed search box, or an empty query if it does
+// not parse.
+func (m Model) parseQuery() *query.Query {
+	q, err := query.Parse(m.searchInput.Value())
+	if err != nil {
+		return &query.Query{}
+	}
+	return q
+}
+
 func (m Model) Selected() string {
 	return m.selected
 }
 
+// SelectedDir returns the existing try that was selected, if any.
+func (m Model) SelectedDir() workspace.Directory {
+	return m.selectedDir
+}
+
+// Sizes returns the tries listed and their sizes by path, or nil sizes
+// when the background scan had not finished.
+func (m Model) Sizes() ([]workspace.Directory, map[string]int64) {
+	return m.directories, m.sizes
+}
+
+// SelectedFile returns the file of a selected content search hit.
+func (m Model) SelectedFile() string {
+	return m.selectedFile
+}
+
+// CreateRoot returns the root chosen for a new try.
+func (m Model) CreateRoot() workspace.Root {
+	if m.createTemp() {
+		return m.tmpRoot
+	}
+	return m.roots[m.createRoot]
+}
+
+// tempPrefix starts a name that asks for a temporary try. The query
+// syntax already takes ! and - for negation.
+const tempPrefix = "~"
+
+// createTemp reports a name typed with a leading tempPrefix.
+func (m Model) createTemp() bool {
+	return strings.HasPrefix(m.parseQuery().Text, tempPrefix)
+}
+
+// CreateTags returns the #tags typed alongside the name of a new try.
+func (m Model) CreateTags() []string {
+	return m.parseQuery().Tags
+}
+
+// CreateTypes returns the project types asked for with lang: alongside the
+// name of a new try.
+func (m Model) CreateTypes() []string {
+	return m.parseQuery().Types
+}
+
+func (m Model) prompting() bool {
+	return m.mode == ModeTags || m.mode == ModeRename || m.mode == ModeFork
+}
+
 func (m Model) Quitting() bool {
 	return m.quitting
 }
@@ -15,6 +15,10 @@ var (
 			Foreground(primaryColor).
 			MarginBottom(1)
 
+	profileStyle = lipgloss.NewStyle().
+			Foreground(accentColor).
+			MarginBottom(1)
+
 	// Search input
 	searchPromptStyle = lipgloss.NewStyle().
 				Foreground(secondaryColor)
@@ -33,6 +37,19 @@ var (
 	dimStyle = lipgloss.NewStyle().
 			Foreground(secondaryColor)
 
+	rootStyle = lipgloss.NewStyle().
+			Foreground(accentColor)
+
+	tagStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("111")) // Blue
+
+	branchStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("114")) // Green
+
+	dirtyStyle = lipgloss.NewStyle().
+			Foreground(accentColor).
+			Bold(true)
+
 	matchStyle = lipgloss.NewStyle().
 			Foreground(accentColor).
 			Bold(true)
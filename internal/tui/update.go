@@ -1,24 +1,98 @@
 package tui
 
 import (
+	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/raiden076/gotry/internal/workspace"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/raiden076/gotry/internal/git"
+	"github.com/raiden076/gotry/internal/workspace"
 )
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+
+	case rootsChangedMsg:
+		m.reloadGen++
+		gen := m.reloadGen
+		return m, tea.Batch(watchRoots(m.watcher), tea.Tick(reloadDelay, func(time.Time) tea.Msg {
+			return reloadMsg{gen}
+		}))
+
+	case reloadMsg:
+		if msg.gen == m.reloadGen {
+			return m, m.loadDirectories
+		}
+		return m, nil
+
 	case dirsLoadedMsg:
 		m.directories = msg.dirs
-		m.filterDirectories()
+		for _, d := range msg.dirs {
+			if _, known := m.gitStatus[d.Path]; !known && d.Git != nil {
+				m.gitStatus[d.Path] = *d.Git
+			}
+		}
+		m.facts = m.newFacts()
+		m.refresh()
+		return m, tea.Batch(scanSizes(msg.dirs), readGitStatus(msg.dirs), readNotes(msg.dirs))
+
+	case grepTickMsg:
+		if msg.gen == m.grepGen && m.mode == ModeGrep {
+			return m, m.runGrep()
+		}
+		return m, nil
+
+	case contentIndexMsg:
+		m.grepContent = msg.content
+		return m, nil
+
+	case grepDoneMsg:
+		if msg.gen == m.grepGen && m.mode == ModeGrep {
+			m.grepHits, m.grepErr = msg.hits, msg.err
+			m.cursor = 0
+		}
+		return m, nil
+
+	case gitStatusMsg:
+		m.gitStatus[msg.path] = msg.status
+		return m, waitGitStatus(msg.next)
+
+	case gitStatusDoneMsg:
+		m.refresh()
+		statuses := make(map[string]git.Status, len(m.gitStatus))
+		for path, st := range m.gitStatus {
+			statuses[path] = st
+		}
+		return m, saveGitStatus(m.directories, statuses)
+
+	case copiedMsg:
+		m.flash = "Copied " + msg.path
+		if msg.err != nil {
+			m.flash = "Copy failed: " + msg.err.Error()
+		}
+		return m, nil
+
+	case notesMsg:
+		for path, n := range msg.notes {
+			m.notes[path] = n
+		}
+		return m, nil
+
+	case sizesMsg:
+		m.sizes = msg.sizes
+		m.facts.Sizes = msg.sizes
+		m.refresh()
 		return m, nil
 
 	case errMsg:
-		// Handle error - for now just quit
-		m.quitting = true
-		return m, tea.Quit
+		// Shown until the next key, leaving the selector usable
+		m.flash = "Error: " + msg.err.Error()
+		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
@@ -26,29 +100,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Update text input
 	var cmd tea.Cmd
-	m.searchInput, cmd = m.searchInput.Update(msg)
-	m.filterDirectories()
-
-	// Reset cursor if out of bounds
-	if m.cursor >= len(m.filtered) {
-		m.cursor = max(0, len(m.filtered)-1)
+	if m.prompting() {
+		m.promptInput, cmd = m.promptInput.Update(msg)
+		return m, cmd
 	}
-
+	if m.mode == ModeGrep {
+		m.searchInput, cmd = m.searchInput.Update(msg)
+		return m, cmd
+	}
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.refresh()
 	return m, cmd
 }
 
 func (m Model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.mode {
+	case ModeTags:
+		return m.handleTagsMode(msg)
+	case ModeRename:
+		return m.handleRenameMode(msg)
+	case ModeFork:
+		return m.handleForkMode(msg)
 	case ModeConfirm:
 		return m.handleConfirmMode(msg)
 	case ModeDelete:
 		return m.handleDeleteMode(msg)
+	case ModeGrep:
+		return m.handleGrepMode(msg)
 	default:
 		return m.handleNormalMode(msg)
 	}
 }
 
 func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.flash = ""
 	switch msg.String() {
 	case "ctrl+c", "esc":
 		m.quitting = true
@@ -74,6 +159,62 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.mode = ModeDelete
 		}
 		return m, nil
+
+	case "ctrl+t":
+		if m.cursor < len(m.filtered) {
+			dir := m.filtered[m.cursor]
+			return m.openPrompt(ModeTags, dir, strings.Join(dir.Meta.Tags, " "))
+		}
+		return m, nil
+
+	case "ctrl+r":
+		if m.cursor < len(m.filtered) && m.inWorkspace(m.filtered[m.cursor]) {
+			return m.openPrompt(ModeRename, m.filtered[m.cursor], m.filtered[m.cursor].NamePart)
+		}
+		return m, nil
+
+	case "ctrl+f":
+		if m.cursor < len(m.filtered) && !m.filtered[m.cursor].Archived() {
+			return m.openPrompt(ModeFork, m.filtered[m.cursor], filepath.Base(m.filtered[m.cursor].NamePart))
+		}
+		return m, nil
+
+	case "ctrl+g":
+		return m.enterGrep()
+
+	case "ctrl+o", "ctrl+s":
+		// Both take over the terminal, so they run after the TUI exits
+		if m.cursor < len(m.filtered) {
+			dir := m.filtered[m.cursor]
+			prefix := map[string]string{"ctrl+o": "EDIT:", "ctrl+s": "SHELL:"}[msg.String()]
+			m.selected = prefix + dir.Path
+			m.selectedDir = dir
+			return m, tea.Quit
+		}
+		return m, nil
+
+	case "ctrl+y":
+		if m.cursor < len(m.filtered) {
+			return m, m.copyPath(m.filtered[m.cursor].Path)
+		}
+		return m, nil
+
+	case "ctrl+e":
+		if m.cursor < len(m.filtered) && !m.filtered[m.cursor].Archived() {
+			return m, m.editNotes(m.filtered[m.cursor])
+		}
+		return m, nil
+
+	case "ctrl+l":
+		m.sortBySize = !m.sortBySize
+		m.filterDirectories()
+		m.cursor = 0
+		return m, nil
+
+	case "tab":
+		// Cycle the root new tries are created in
+		m.createRoot = (m.createRoot + 1) % len(m.roots)
+		return m, nil
 	}
 
 	// Pass to text input
@@ -88,11 +229,97 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+func (m Model) handleTagsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m.closePrompt(), nil
+
+	case "enter":
+		dir := m.target
+		tags := strings.Fields(m.promptInput.Value())
+		err := workspace.UpdateMeta(dir.RootPath, func(meta *workspace.Meta) error {
+			entry := meta.Entry(dir.Name)
+			entry.Tags = nil
+			entry.AddTags(tags...)
+			return nil
+		})
+		m = m.closePrompt()
+		if err != nil {
+			return m, func() tea.Msg { return errMsg{err} }
+		}
+		return m, m.loadDirectories
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleRenameMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m.closePrompt(), nil
+
+	case "enter":
+		dir := m.target
+		name, err := workspace.Rename(dir, m.promptInput.Value(), m.naming)
+		if err == nil {
+			err = git.RepairWorktrees(filepath.Join(dir.RootPath, name))
+		}
+		m = m.closePrompt()
+		if err != nil {
+			return m, func() tea.Msg { return errMsg{err} }
+		}
+		return m, m.loadDirectories
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handleForkMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc":
+		return m.closePrompt(), nil
+
+	case "enter":
+		// Forking runs git, so it happens after the TUI exits
+		m.selectedDir = m.target
+		m.selected = "FORK:" + m.promptInput.Value()
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.promptInput, cmd = m.promptInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) openPrompt(mode Mode, dir workspace.Directory, value string) (tea.Model, tea.Cmd) {
+	m.mode = mode
+	m.target = dir
+	m.promptInput.SetValue(value)
+	m.promptInput.CursorEnd()
+	m.searchInput.Blur()
+	return m, m.promptInput.Focus()
+}
+
+func (m Model) closePrompt() Model {
+	m.mode = ModeNormal
+	m.target = workspace.Directory{}
+	m.promptInput.Blur()
+	m.promptInput.SetValue("")
+	m.searchInput.Focus()
+	m.refresh()
+	return m
+}
+
 func (m Model) handleDeleteMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "esc":
 		m.mode = ModeNormal
-		m.marked = make(map[int]bool)
+		m.marked = make(map[string]workspace.Directory)
+		m.refresh()
 		return m, nil
 
 	case "enter":
@@ -117,10 +344,11 @@ func (m Model) handleDeleteMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "ctrl+d", " ":
 		// Toggle mark on current item
 		if m.cursor < len(m.filtered) {
-			if m.marked[m.cursor] {
-				delete(m.marked, m.cursor)
+			dir := m.filtered[m.cursor]
+			if _, ok := m.marked[dir.Path]; ok {
+				delete(m.marked, dir.Path)
 			} else {
-				m.marked[m.cursor] = true
+				m.marked[dir.Path] = dir
 			}
 		}
 		return m, nil
@@ -157,17 +385,18 @@ func (m Model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) handleEnter() (tea.Model, tea.Cmd) {
-	query := m.searchInput.Value()
+	query := m.parseQuery().Text
 
 	if len(m.filtered) > 0 && m.cursor < len(m.filtered) {
 		// Select existing directory
 		m.selected = m.filtered[m.cursor].Path
+		m.selectedDir = m.filtered[m.cursor]
 		return m, tea.Quit
 	}
 
-	if query != "" {
-		// Create new directory
-		m.selected = "CREATE:" + query
+	if name := strings.TrimPrefix(query, tempPrefix); name != "" {
+		// Create new directory, temporary with a leading ~
+		m.selected = "CREATE:" + name
 		return m, tea.Quit
 	}
 
@@ -175,28 +404,32 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 }
 
 func (m Model) executeDelete() (tea.Model, tea.Cmd) {
-	var paths []string
-	for idx := range m.marked {
-		if idx < len(m.filtered) {
-			paths = append(paths, m.filtered[idx].Path)
+	var err error
+	for _, dir := range m.marked {
+		if err = workspace.Discard(dir); err != nil {
+			break
 		}
-	}
-
-	if err := workspace.Delete(paths); err != nil {
-		// Handle error
-		m.mode = ModeNormal
-		m.marked = make(map[int]bool)
-		return m, nil
+		m.mux.Kill(dir.Name)
 	}
 
 	// Reload directories
 	m.mode = ModeNormal
-	m.marked = make(map[int]bool)
+	m.marked = make(map[string]workspace.Directory)
 	m.confirmText = ""
+	m.refresh()
+	if err != nil {
+		m.flash = "Error: " + err.Error()
+	}
 
 	return m, m.loadDirectories
 }
 
+// inWorkspace reports whether a try's files are in its root, rather than
+// graduated elsewhere or packed in an archive.
+func (m Model) inWorkspace(dir workspace.Directory) bool {
+	return !dir.Graduated() && !dir.Archived()
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/raiden076/gotry/internal/git"
 	"github.com/raiden076/gotry/internal/workspace"
 )
 
@@ -15,37 +16,89 @@ func (m Model) View() string {
 	var b strings.Builder
 
 	// Title
-	b.WriteString(titleStyle.Render("gotry"))
+	title := titleStyle.Render("gotry")
+	if m.profile != "" {
+		title += profileStyle.Render(" · " + m.profile)
+	}
+	b.WriteString(title)
 	b.WriteString("\n")
 
 	// Search input
-	b.WriteString(searchPromptStyle.Render("Search: "))
+	label := "Search: "
+	if m.mode == ModeGrep {
+		label = "Grep:   "
+	}
+	b.WriteString(searchPromptStyle.Render(label))
 	b.WriteString(m.searchInput.View())
 	b.WriteString("\n\n")
 
 	// Directory list
-	if len(m.filtered) == 0 {
-		if m.searchInput.Value() != "" {
+	if m.mode == ModeGrep {
+		b.WriteString(m.renderGrep())
+	} else if len(m.filtered) == 0 {
+		q := m.parseQuery()
+		if m.queryErr != nil {
+			b.WriteString(markedStyle.Render("  " + m.queryErr.Error()))
+		} else if q.Text != "" {
 			b.WriteString(dimStyle.Render("  No matches. Press enter to create: "))
-			b.WriteString(normalStyle.Render(m.searchInput.Value()))
+			b.WriteString(normalStyle.Render(strings.TrimPrefix(q.Text, tempPrefix)))
+			if len(m.roots) > 1 || m.createTemp() {
+				b.WriteString(dimStyle.Render(" in "))
+				b.WriteString(rootStyle.Render(m.CreateRoot().Name))
+			}
+		} else if m.searchInput.Value() != "" {
+			b.WriteString(dimStyle.Render("  No tries match " + m.searchInput.Value()))
 		} else {
 			b.WriteString(dimStyle.Render("  No experiments yet. Type a name to create one."))
 		}
 		b.WriteString("\n")
 	} else {
-		for i, dir := range m.filtered {
-			b.WriteString(m.renderDirectoryItem(i, dir))
+		// Only the rows that fit are rendered, keeping the cursor in view
+		start, end := m.visibleRange(len(m.filtered), 1)
+		for i := start; i < end; i++ {
+			b.WriteString(m.renderDirectoryItem(i, m.filtered[i]))
+			b.WriteString("\n")
+		}
+		if hidden := len(m.filtered) - (end - start); hidden > 0 {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("   … %d more", hidden)))
 			b.WriteString("\n")
 		}
+		b.WriteString(m.renderNotePreview())
 	}
 
 	// Footer
 	b.WriteString("\n")
+	if m.flash != "" {
+		b.WriteString(dimStyle.Render(m.flash))
+		b.WriteString("\n")
+	}
 	b.WriteString(m.renderFooter())
 
 	return b.String()
 }
 
+// chromeLines is the height taken by the title, search box, note preview
+// and footer.
+const chromeLines = 10
+
+// visibleRange returns the slice of rows, each rowLines tall, that fits the
+// terminal with the cursor on screen. Before the terminal size is known
+// everything is shown.
+func (m Model) visibleRange(rows, rowLines int) (int, int) {
+	if m.height == 0 {
+		return 0, rows
+	}
+	fit := max(3, (m.height-chromeLines)/rowLines)
+	if rows <= fit {
+		return 0, rows
+	}
+	start := 0
+	if m.cursor >= fit {
+		start = m.cursor - fit + 1
+	}
+	return start, start + fit
+}
+
 func (m Model) renderDirectoryItem(index int, dir workspace.Directory) string {
 	var b strings.Builder
 
@@ -56,26 +109,30 @@ func (m Model) renderDirectoryItem(index int, dir workspace.Directory) string {
 		b.WriteString("   ")
 	}
 
+	_, marked := m.marked[dir.Path]
+
 	// Icon
-	if m.mode == ModeDelete && m.marked[index] {
-		b.WriteString(markedStyle.Render("🗑️  "))
+	if m.mode == ModeDelete && marked {
+		b.WriteString(markedStyle.Render(m.icon("🗑️  ", " x ")))
 	} else {
-		b.WriteString("📁 ")
+		b.WriteString(m.typeIcon(dir))
 	}
 
 	// Directory name
 	name := dir.Name
-	if m.mode == ModeDelete && m.marked[index] {
+	if m.mode == ModeDelete && marked {
 		name = deleteStyle.Render(name)
+	} else if dir.Archived() {
+		name = dimStyle.Render(name)
 	} else if index == m.cursor {
 		if dir.DatePart != "" {
-			name = dimStyle.Render(dir.DatePart+"-") + selectedStyle.Render(dir.NamePart)
+			name = dimStyle.Render(dir.DatePart) + selectedStyle.Render(dir.NamePart)
 		} else {
 			name = selectedStyle.Render(name)
 		}
 	} else {
 		if dir.DatePart != "" {
-			name = dimStyle.Render(dir.DatePart+"-") + normalStyle.Render(dir.NamePart)
+			name = dimStyle.Render(dir.DatePart) + normalStyle.Render(dir.NamePart)
 		} else {
 			name = normalStyle.Render(name)
 		}
@@ -83,7 +140,7 @@ func (m Model) renderDirectoryItem(index int, dir workspace.Directory) string {
 	b.WriteString(name)
 
 	// Relative time
-	relTime := workspace.RelativeTime(dir.ModTime)
+	relTime := workspace.RelativeTime(dir.LastUsed())
 	padding := 40 - len(dir.Name)
 	if padding < 2 {
 		padding = 2
@@ -91,11 +148,93 @@ func (m Model) renderDirectoryItem(index int, dir workspace.Directory) string {
 	b.WriteString(strings.Repeat(" ", padding))
 	b.WriteString(dimStyle.Render(relTime))
 
+	if dir.Temporary() {
+		b.WriteString(dimStyle.Render(" " + m.icon("⏳ ", "ttl") + dir.TimeLeft()))
+	}
+
+	// Toolchain environment
+	if !dir.Archived() {
+		if env := m.facts.Env(dir.Path); env != "" {
+			b.WriteString(dimStyle.Render(" " + m.icon("🌱 ", "env") + env))
+		}
+	}
+
+	// Size, once scanned
+	if size, ok := m.sizes[dir.Path]; ok {
+		b.WriteString(dimStyle.Render(fmt.Sprintf(" %7s", workspace.HumanSize(size))))
+	}
+
+	// Git state, as it streams in
+	if status, ok := m.gitStatus[dir.Path]; ok {
+		b.WriteString(" ")
+		b.WriteString(renderGitStatus(status))
+	}
+
+	// Root badge
+	if len(m.roots) > 1 {
+		b.WriteString(" ")
+		b.WriteString(rootStyle.Render("[" + dir.Root + "]"))
+	}
+
+	if dir.Graduated() {
+		b.WriteString(dimStyle.Render(" → " + dir.Path))
+	}
+
+	// Tags and description
+	if dir.Meta.Pinned {
+		b.WriteString(" 📌")
+	}
+	for _, tag := range dir.Meta.Tags {
+		b.WriteString(" ")
+		b.WriteString(tagStyle.Render("#" + tag))
+	}
+	if dir.Meta.Description != "" {
+		b.WriteString(dimStyle.Render("  " + dir.Meta.Description))
+	}
+
 	return b.String()
 }
 
+// renderNotePreview shows the first line of the latest note of the try
+// under the cursor.
+func (m Model) renderNotePreview() string {
+	if m.mode != ModeNormal || m.cursor >= len(m.filtered) {
+		return ""
+	}
+	note, ok := m.notes[m.filtered[m.cursor].Path]
+	if !ok {
+		return ""
+	}
+	text, _, more := strings.Cut(note.Text, "\n")
+	if more {
+		text += " …"
+	}
+	return "\n" + dimStyle.Render("   "+m.icon("📝 ", "  >")+workspace.RelativeTime(note.Time)+" · ") +
+		normalStyle.Render(truncate(text, 72)) + "\n"
+}
+
 func (m Model) renderFooter() string {
 	switch m.mode {
+	case ModeTags, ModeRename, ModeFork:
+		label := map[Mode]string{
+			ModeTags:   "Tags:",
+			ModeRename: "Rename:",
+			ModeFork:   "Fork as:",
+		}[m.mode]
+		return fmt.Sprintf(
+			"%s %s\n%s · %s",
+			searchPromptStyle.Render(label),
+			m.promptInput.View(),
+			helpKeyStyle.Render("enter")+" "+helpDescStyle.Render("save"),
+			helpKeyStyle.Render("esc")+" "+helpDescStyle.Render("cancel"),
+		)
+
+	case ModeGrep:
+		return strings.Join([]string{
+			helpItem("enter", "open file's try"),
+			helpItem("esc", "back to tries"),
+		}, " · ")
+
 	case ModeConfirm:
 		return fmt.Sprintf(
 			"%s Type %s to confirm deletion (%d items): %s",
@@ -122,11 +261,75 @@ func (m Model) renderFooter() string {
 		)
 
 	default:
-		return fmt.Sprintf(
-			"%s · %s · %s",
-			helpKeyStyle.Render("enter")+" "+helpDescStyle.Render("select"),
-			helpKeyStyle.Render("ctrl+d")+" "+helpDescStyle.Render("delete"),
-			helpKeyStyle.Render("esc")+" "+helpDescStyle.Render("quit"),
+		items := []string{helpItem("enter", "select")}
+		if len(m.roots) > 1 {
+			items = append(items, helpItem("tab", "create in "+m.CreateRoot().Name))
+		}
+		items = append(items,
+			helpItem("ctrl+o", "edit"),
+			helpItem("ctrl+s", "shell"),
+			helpItem("ctrl+y", "copy path"),
+			helpItem("ctrl+t", "tags"),
+			helpItem("ctrl+r", "rename"),
+			helpItem("ctrl+f", "fork"),
+			helpItem("ctrl+e", "notes"),
+			helpItem("ctrl+g", "grep"),
+			helpItem("ctrl+l", sortLabel(m.sortBySize)),
+			helpItem("ctrl+d", "delete"),
+			helpItem("esc", "quit"),
 		)
+		return strings.Join(items, " · ")
+	}
+}
+
+func (m Model) icon(emoji, ascii string) string {
+	if m.ascii {
+		return ascii + " "
+	}
+	return emoji
+}
+
+// typeIcon shows the first detected project type of a try.
+func (m Model) typeIcon(dir workspace.Directory) string {
+	if dir.Archived() {
+		return m.icon("📦 ", "arc")
+	}
+	if types := m.facts.Types(dir.Path); len(types) > 0 {
+		if t, ok := workspace.LookupType(types[0]); ok {
+			return m.icon(t.Icon+" ", t.ASCII)
+		}
+	}
+	return m.icon("📁 ", "   ")
+}
+
+func renderGitStatus(s git.Status) string {
+	branch := s.Branch
+	if branch == "" {
+		branch = "detached"
+	}
+	out := branchStyle.Render(branch)
+	if s.Dirty {
+		out += dirtyStyle.Render("*")
 	}
+	if s.Ahead > 0 {
+		out += dimStyle.Render(fmt.Sprintf(" ↑%d", s.Ahead))
+	}
+	if s.Behind > 0 {
+		out += dimStyle.Render(fmt.Sprintf(" ↓%d", s.Behind))
+	}
+	if !s.LastCommit.IsZero() {
+		out += dimStyle.Render(" @" + workspace.RelativeTime(s.LastCommit))
+	}
+	return out
+}
+
+func sortLabel(bySize bool) string {
+	if bySize {
+		return "sort by recency"
+	}
+	return "sort by size"
+}
+
+func helpItem(key, desc string) string {
+	return helpKeyStyle.Render(key) + " " + helpDescStyle.Render(desc)
 }
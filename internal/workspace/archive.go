@@ -0,0 +1,384 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FormatGzip = "tar.gz"
+	FormatZstd = "tar.zst"
+)
+
+// DefaultArtifactDirs are dependency and build folders that can be left
+// out of archives because they are regenerable.
+var DefaultArtifactDirs = []string{
+	"node_modules", "target", ".venv", "venv", "__pycache__",
+	".gradle", "build", "dist", ".next", ".cache", "vendor",
+}
+
+// ArchiveName is the file name a try is archived under.
+func ArchiveName(name, format string) string {
+	return strings.ReplaceAll(filepath.ToSlash(name), "/", "__") + "." + format
+}
+
+// Archive writes the tree at src into a compressed tarball at dest.
+// Folders whose base name is in exclude are skipped, except for the files
+// in tracked (slash-separated, relative to src) and the folders leading to
+// them. tar.zst needs the zstd binary.
+func Archive(src, dest, format string, exclude, tracked []string) (err error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+	defer f.Close()
+
+	compressed, finish, err := compressor(f, format)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(compressed)
+	err = writeTree(tw, src, excluder(exclude, tracked))
+	if err == nil {
+		err = tw.Close()
+	}
+	// Finished on failure too, so zstd is never left running
+	if ferr := finish(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func compressor(w io.Writer, format string) (io.Writer, func() error, error) {
+	switch format {
+	case FormatGzip:
+		gz := gzip.NewWriter(w)
+		return gz, gz.Close, nil
+
+	case FormatZstd:
+		cmd := exec.Command("zstd", "-q", "-c")
+		cmd.Stdout = w
+		cmd.Stderr = os.Stderr
+		in, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, fmt.Errorf("tar.zst needs zstd installed: %w", err)
+		}
+		return in, func() error {
+			in.Close()
+			return cmd.Wait()
+		}, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported archive format %q (use %s or %s)", format, FormatGzip, FormatZstd)
+}
+
+// excluder returns whether a path, relative to the archived tree, is left
+// out. Paths inside excluded folders are, unless git tracks them, they lead
+// to tracked files, or they are inside a tracked folder (a submodule).
+func excluder(exclude, tracked []string) func(rel string, isDir bool) bool {
+	keep := make(map[string]bool, len(tracked))
+	leads := map[string]bool{}
+	for _, t := range tracked {
+		keep[t] = true
+		for dir := path.Dir(t); dir != "."; dir = path.Dir(dir) {
+			leads[dir] = true
+		}
+	}
+
+	return func(rel string, isDir bool) bool {
+		parts := strings.Split(rel, "/")
+		folders := len(parts)
+		if !isDir {
+			folders--
+		}
+		excluded := -1
+		for i := range folders {
+			if contains(exclude, parts[i]) {
+				excluded = i
+				break
+			}
+		}
+		if excluded < 0 || keep[rel] || leads[rel] {
+			return false
+		}
+		for i := excluded + 1; i < len(parts); i++ {
+			if keep[strings.Join(parts[:i], "/")] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func writeTree(tw *tar.Writer, src string, excluded func(rel string, isDir bool) bool) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if excluded(filepath.ToSlash(rel), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+}
+
+// Extract unpacks an archive written by Archive into dest, which must not
+// exist yet.
+func Extract(archive, dest string) (err error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, finish, err := decompressor(f, archive)
+	if err != nil {
+		return err
+	}
+	finished := false
+	defer func() {
+		if !finished {
+			finish()
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.Mkdir(dest, 0755); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dest)
+		}
+	}()
+
+	tr := tar.NewReader(r)
+	var dirs []*tar.Header
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(hdr.Name))
+		if !Within(dest, target) {
+			return fmt.Errorf("archive entry %q escapes %s", hdr.Name, dest)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, hdr)
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, hdr.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+			os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+		}
+	}
+
+	// tar stops at its end marker, or at the end of whatever was
+	// decompressed, so the stream is read to its end and the decompressor
+	// checked for damage that fell between entries
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+	finished = true
+	if err := finish(); err != nil {
+		return fmt.Errorf("%s is damaged: %w", archive, err)
+	}
+
+	// Directory modes and times last, after their contents are written
+	for _, hdr := range dirs {
+		target := filepath.Join(dest, filepath.FromSlash(hdr.Name))
+		os.Chmod(target, hdr.FileInfo().Mode().Perm())
+		os.Chtimes(target, hdr.ModTime, hdr.ModTime)
+	}
+	return nil
+}
+
+func decompressor(f *os.File, name string) (io.Reader, func() error, error) {
+	switch {
+	case strings.HasSuffix(name, "."+FormatGzip):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, gz.Close, nil
+
+	case strings.HasSuffix(name, "."+FormatZstd):
+		cmd := exec.Command("zstd", "-q", "-d", "-c")
+		cmd.Stdin = f
+		cmd.Stderr = os.Stderr
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, fmt.Errorf("tar.zst needs zstd installed: %w", err)
+		}
+		return out, func() error {
+			out.Close()
+			return cmd.Wait()
+		}, nil
+	}
+	return nil, nil, fmt.Errorf("unknown archive format: %s", name)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveTry archives a try into archiveDir, removes it, and records the
+// archive in the metadata index so the try stays listed. exclude and
+// tracked are as for Archive.
+func ArchiveTry(d Directory, archiveDir, format string, exclude, tracked []string) (string, error) {
+	if d.Archived() || d.Graduated() {
+		return "", fmt.Errorf("%s is not in the workspace", d.Name)
+	}
+
+	// Removing a linked try would only remove the link
+	if info, err := os.Lstat(d.Path); err != nil {
+		return "", err
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		target, _ := filepath.EvalSymlinks(d.Path)
+		return "", fmt.Errorf("%s links to %s, which gotry does not own", d.Name, target)
+	}
+
+	dest := filepath.Join(archiveDir, ArchiveName(d.Name, format))
+	if err := Archive(d.Path, dest, format, exclude, tracked); err != nil {
+		return "", err
+	}
+
+	err := UpdateMeta(d.RootPath, func(m *Meta) error {
+		entry := m.Entry(d.Name)
+		entry.Archive = dest
+		entry.ArchivedAt = time.Now()
+		if entry.LastAccess.Before(d.LastUsed()) {
+			entry.LastAccess = d.LastUsed()
+		}
+		return nil
+	})
+	if err != nil {
+		os.Remove(dest)
+		return "", err
+	}
+
+	if err := os.RemoveAll(d.Path); err != nil {
+		return "", err
+	}
+	pruneEmptyParents(d.RootPath, filepath.Dir(d.Path))
+	return dest, nil
+}
+
+// RestoreTry unpacks an archived try back into place and deletes the
+// archive.
+func RestoreTry(d Directory) error {
+	if !d.Archived() {
+		return fmt.Errorf("%s is not archived", d.Name)
+	}
+
+	if err := Extract(d.Meta.Archive, d.Path); err != nil {
+		return err
+	}
+
+	err := UpdateMeta(d.RootPath, func(m *Meta) error {
+		entry := m.Entry(d.Name)
+		entry.Archive = ""
+		entry.ArchivedAt = time.Time{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return os.Remove(d.Meta.Archive)
+}
@@ -0,0 +1,101 @@
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatGzip, FormatZstd} {
+		t.Run(format, func(t *testing.T) {
+			if format == FormatZstd {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd is not installed")
+				}
+			}
+			src := t.TempDir()
+			for name, data := range map[string]string{
+				"main.go":                  "package main\n",
+				"node_modules/x/index.js":  "x",
+				"vendor/lib/kept.go":       "package lib\n",
+				"vendor/lib/untracked.txt": "gone",
+			} {
+				path := filepath.Join(src, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			archive := filepath.Join(t.TempDir(), "try."+format)
+			exclude := []string{"node_modules", "vendor"}
+			if err := Archive(src, archive, format, exclude, []string{"vendor/lib/kept.go"}); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(t.TempDir(), "restored")
+			if err := Extract(archive, dest); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range map[string]bool{
+				"main.go":                  true,
+				"node_modules":             false,
+				"vendor/lib/kept.go":       true,
+				"vendor/lib/untracked.txt": false,
+			} {
+				_, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name)))
+				if got := err == nil; got != want {
+					t.Errorf("%s restored: %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// A zstd stream that breaks off right after a complete tar entry leaves tar
+// with what looks like a short but intact archive; only zstd's exit status
+// tells.
+func TestExtractDamagedZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not installed")
+	}
+
+	var raw bytes.Buffer
+	tw := tar.NewWriter(&raw)
+	data := []byte("package main\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "main.go", Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(data)
+	tw.Flush() // no end marker
+
+	compress := func(b []byte) []byte {
+		cmd := exec.Command("zstd", "-q", "-c")
+		cmd.Stdin = bytes.NewReader(b)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	next := compress(bytes.Repeat([]byte("more entries "), 1000))
+	stream := append(compress(raw.Bytes()), next[:len(next)/2]...)
+
+	archive := filepath.Join(t.TempDir(), "try."+FormatZstd)
+	if err := os.WriteFile(archive, stream, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "restored")
+	if err := Extract(archive, dest); err == nil {
+		t.Fatal("damaged archive extracted without error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("partial restore left at %s", dest)
+	}
+}
@@ -0,0 +1,119 @@
+package workspace_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/raiden076/gotry/internal/query"
+	"github.com/raiden076/gotry/internal/workspace"
+)
+
+// benchTries is the workspace size listing and filtering are measured on.
+// With one CPU, listing takes about 80ms cold and 30ms warm, and filtering
+// 0.4 to 30ms a keystroke.
+const benchTries = 10000
+
+// generateRoot fills a temporary root with n small tries, twenty a day.
+func generateRoot(b *testing.B, n int) workspace.Root {
+	b.Helper()
+	base := b.TempDir()
+	words := []string{"api", "server", "parser", "cli", "demo", "spike", "proto", "ui", "cache", "queue"}
+	day := time.Now()
+	for i := range n {
+		slug := fmt.Sprintf("%s-%s-%d", words[i%len(words)], words[(i/len(words))%len(words)], i)
+		name, err := workspace.DefaultNaming.TryNameAt(slug, day.AddDate(0, 0, -i/20))
+		if err != nil {
+			b.Fatal(err)
+		}
+		dir := filepath.Join(base, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		src := fmt.Sprintf("package main\n\n// %s\nfunc run%d() {}\n", slug, i)
+		if i%50 == 0 {
+			src += "\nfunc main() {}\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return workspace.Root{Name: "bench", Path: base}
+}
+
+func BenchmarkListRootsCold(b *testing.B) {
+	roots := []workspace.Root{generateRoot(b, benchTries)}
+	for b.Loop() {
+		b.StopTimer()
+		if err := workspace.RemoveIndex(roots[0].Path); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if _, err := workspace.ListRoots(roots, workspace.DefaultNaming); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListRootsWarm(b *testing.B) {
+	roots := []workspace.Root{generateRoot(b, benchTries)}
+	if _, err := workspace.ListRoots(roots, workspace.DefaultNaming); err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		if _, err := workspace.ListRoots(roots, workspace.DefaultNaming); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFilter types a query into the selector one keystroke at a time.
+func BenchmarkFilter(b *testing.B) {
+	dirs, err := workspace.ListRoots([]workspace.Root{generateRoot(b, benchTries)}, workspace.DefaultNaming)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, text := range []string{"a", "api", "api-serv", "api tag:x", "age:<7d api"} {
+		q, err := query.Parse(text)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(text, func(b *testing.B) {
+			facts := query.NewFacts()
+			for b.Loop() {
+				q.Filter(dirs, facts)
+			}
+		})
+	}
+}
+
+func BenchmarkGrep(b *testing.B) {
+	root := generateRoot(b, benchTries)
+	dirs, err := workspace.ListRoots([]workspace.Root{root}, workspace.DefaultNaming)
+	if err != nil {
+		b.Fatal(err)
+	}
+	re := regexp.MustCompile(`func main`)
+	grep := func(b *testing.B, content map[string]*workspace.ContentIndex) {
+		opts := workspace.GrepOptions{Content: content}
+		for b.Loop() {
+			workspace.Grep(dirs, re, opts, func([]workspace.Hit) bool { return true })
+		}
+	}
+
+	b.Run("files", func(b *testing.B) { grep(b, nil) })
+
+	content := workspace.NewContentIndex(root.Path)
+	for _, d := range dirs {
+		if _, err := content.Update(d, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := content.Save(); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("content", func(b *testing.B) { grep(b, workspace.LoadContentIndexes(dirs)) })
+}
@@ -0,0 +1,223 @@
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const cleanStampFile = "cleaned"
+
+// ArtifactRule describes the regenerable folders one ecosystem leaves
+// behind. Names are glob patterns matched against folder names; when
+// Markers is set, a folder only counts if one of the marker files sits
+// next to it. Rules for a known project type default to its markers.
+type ArtifactRule struct {
+	Names   []string `mapstructure:"names"`
+	Markers []string `mapstructure:"markers"`
+}
+
+// DefaultArtifactRules is the built-in catalogue, keyed by ecosystem.
+var DefaultArtifactRules = map[string]ArtifactRule{
+	"node": {
+		Names: []string{"node_modules", ".next", ".nuxt", ".svelte-kit", ".turbo", ".parcel-cache", "dist"},
+	},
+	"rust": {
+		Names: []string{"target"},
+	},
+	"python": {
+		Names: []string{".venv", ".pytest_cache", ".mypy_cache", ".ruff_cache", ".tox"},
+	},
+	"pycache": {
+		Names: []string{"__pycache__"},
+	},
+	"gradle": {
+		Names:   []string{".gradle", "build"},
+		Markers: []string{"build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+	},
+	"maven": {
+		Names:   []string{"target"},
+		Markers: []string{"pom.xml"},
+	},
+	"zig": {
+		Names:   []string{".zig-cache", "zig-cache", "zig-out"},
+		Markers: []string{"build.zig"},
+	},
+	"elixir": {
+		Names:   []string{"_build", "deps"},
+		Markers: []string{"mix.exs"},
+	},
+}
+
+// Artifact is a regenerable folder found inside a try.
+type Artifact struct {
+	Path      string
+	Ecosystem string
+	Size      int64
+
+	// keep holds the files below Path that are tracked by git and must
+	// survive cleaning.
+	keep map[string]bool
+}
+
+// FindArtifacts walks a try looking for folders matching rules. tracked
+// lists git-tracked files as slash-separated paths relative to path; they
+// are left alone and not counted as reclaimable, and so are submodules,
+// which git lists as tracked folders.
+func FindArtifacts(path string, rules map[string]ArtifactRule, tracked []string) ([]Artifact, error) {
+	keep := make(map[string]bool, len(tracked))
+	for _, rel := range tracked {
+		keep[filepath.Join(path, filepath.FromSlash(rel))] = true
+	}
+
+	ecosystems := make([]string, 0, len(rules))
+	for name := range rules {
+		ecosystems = append(ecosystems, name)
+	}
+	sort.Strings(ecosystems)
+
+	var artifacts []Artifact
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsPermission(err) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() || p == path {
+			return nil
+		}
+		if d.Name() == ".git" || d.Name() == MetaDir || keep[p] {
+			return filepath.SkipDir
+		}
+
+		for _, eco := range ecosystems {
+			if !rules[eco].withTypeMarkers(eco).matches(p, d.Name()) {
+				continue
+			}
+			a := Artifact{Path: p, Ecosystem: eco, keep: map[string]bool{}}
+			a.measure(keep)
+			if a.Size > 0 || len(a.keep) == 0 {
+				artifacts = append(artifacts, a)
+			}
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return artifacts, err
+}
+
+func (r ArtifactRule) withTypeMarkers(eco string) ArtifactRule {
+	if t, ok := LookupType(eco); ok && len(r.Markers) == 0 {
+		r.Markers = t.Markers
+	}
+	return r
+}
+
+func (r ArtifactRule) matches(path, name string) bool {
+	found := false
+	for _, pattern := range r.Names {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	if len(r.Markers) == 0 {
+		return true
+	}
+	for _, marker := range r.Markers {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// measure totals the untracked files below the artifact and records the
+// tracked ones.
+func (a *Artifact) measure(tracked map[string]bool) {
+	filepath.WalkDir(a.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if tracked[p] {
+			a.keep[p] = true
+			if d.IsDir() {
+				return filepath.SkipDir // a submodule
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && d.Type().IsRegular() {
+			a.Size += info.Size()
+		}
+		return nil
+	})
+}
+
+// Remove deletes the artifact except for its git-tracked files. The
+// modification time of the enclosing folder is preserved so cleaning does
+// not make a try look recently used.
+func (a Artifact) Remove() error {
+	parent := filepath.Dir(a.Path)
+	if info, err := os.Stat(parent); err == nil {
+		defer os.Chtimes(parent, info.ModTime(), info.ModTime())
+	}
+
+	if len(a.keep) == 0 {
+		return os.RemoveAll(a.Path)
+	}
+
+	// Remove untracked files, then whatever directories end up empty,
+	// deepest first
+	var dirs []string
+	err := filepath.WalkDir(a.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if a.keep[p] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+		return os.Remove(p)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Remove(dirs[i]) // fails harmlessly while tracked files remain
+	}
+	return nil
+}
+
+// CleanDue reports whether the automatic cleaning of a root last ran more
+// than every ago, and if so records that it runs now. Checking costs one
+// stat, so launches only pay for a cleaning pass once in a while.
+func CleanDue(basePath string, every time.Duration) bool {
+	stamp := filepath.Join(basePath, MetaDir, cleanStampFile)
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < every {
+		return false
+	}
+	if err := os.MkdirAll(filepath.Join(basePath, MetaDir), 0755); err != nil {
+		return false
+	}
+	if err := os.WriteFile(stamp, nil, 0644); err != nil {
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(stamp, now, now)
+	return true
+}
@@ -0,0 +1,188 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EnvKind is a way of declaring a try's toolchain, recognised by its
+// files and loaded by direnv with its .envrc line.
+type EnvKind struct {
+	Name    string
+	Markers []string
+	Envrc   string
+}
+
+// EnvKinds lists the known environments, most specific first.
+var EnvKinds = []EnvKind{
+	{Name: "flake", Markers: []string{"flake.nix"}, Envrc: "use flake"},
+	{Name: "nix", Markers: []string{"shell.nix", "default.nix"}, Envrc: "use nix"},
+	{Name: "devbox", Markers: []string{"devbox.json"}, Envrc: `eval "$(devbox generate direnv --print-envrc)"`},
+	// asdf's shims pick versions from .tool-versions, so this needs no
+	// direnv plugin
+	{Name: "asdf", Markers: []string{".tool-versions"}, Envrc: `PATH_add "${ASDF_DATA_DIR:-$HOME/.asdf}/shims"`},
+	{Name: "direnv", Markers: []string{".envrc"}},
+}
+
+// EnvPackages are the packages an environment provides for a project
+// type, named as each tool expects.
+type EnvPackages struct {
+	Nix    []string `mapstructure:"nix"`    // nixpkgs attributes
+	Devbox []string `mapstructure:"devbox"` // devbox packages
+	Asdf   []string `mapstructure:"asdf"`   // asdf plugins
+}
+
+// DefaultEnvPackages holds the packages for each project type.
+var DefaultEnvPackages = map[string]EnvPackages{
+	"go":     {Nix: []string{"go", "gopls"}, Devbox: []string{"go@latest", "gopls@latest"}, Asdf: []string{"golang"}},
+	"rust":   {Nix: []string{"cargo", "rustc", "rust-analyzer"}, Devbox: []string{"rustup@latest"}, Asdf: []string{"rust"}},
+	"node":   {Nix: []string{"nodejs"}, Devbox: []string{"nodejs@latest"}, Asdf: []string{"nodejs"}},
+	"python": {Nix: []string{"python3"}, Devbox: []string{"python@latest"}, Asdf: []string{"python"}},
+	"ruby":   {Nix: []string{"ruby"}, Devbox: []string{"ruby@latest"}, Asdf: []string{"ruby"}},
+}
+
+// LookupEnv returns the environment kind with the given name.
+func LookupEnv(name string) (EnvKind, bool) {
+	for _, k := range EnvKinds {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return EnvKind{}, false
+}
+
+// DetectEnv returns the kind of environment defined at the top level of
+// path, or "".
+func DetectEnv(path string) string {
+	for _, k := range EnvKinds {
+		for _, marker := range k.Markers {
+			if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+				return k.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Bootstrap writes the files declaring an environment of the given kind
+// with the packages of types, and an .envrc loading it. Files that exist,
+// say from a template, are left alone.
+func Bootstrap(path, kind string, types []string, packages map[string]EnvPackages) error {
+	k, ok := LookupEnv(kind)
+	if !ok {
+		return fmt.Errorf("unknown environment %q (supported: flake, nix, devbox, asdf, direnv)", kind)
+	}
+
+	var pkgs EnvPackages
+	for _, t := range types {
+		p := packages[t]
+		pkgs.Nix = append(pkgs.Nix, p.Nix...)
+		pkgs.Devbox = append(pkgs.Devbox, p.Devbox...)
+		pkgs.Asdf = append(pkgs.Asdf, p.Asdf...)
+	}
+
+	files := map[string]string{}
+	switch kind {
+	case "flake":
+		files["flake.nix"] = flakeNix(pkgs.Nix)
+	case "nix":
+		files["shell.nix"] = shellNix(pkgs.Nix)
+	case "devbox":
+		data, err := json.MarshalIndent(map[string]any{"packages": orEmpty(pkgs.Devbox)}, "", "  ")
+		if err != nil {
+			return err
+		}
+		files["devbox.json"] = string(data) + "\n"
+	case "asdf":
+		files[".tool-versions"] = toolVersions(pkgs.Asdf)
+	}
+	files[".envrc"] = k.Envrc + "\n"
+	if k.Envrc == "" {
+		files[".envrc"] = "# Environment of this try, loaded by direnv\n"
+	}
+
+	for name, content := range files {
+		p := filepath.Join(path, name)
+		if _, err := os.Stat(p); err == nil {
+			continue
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllowDirenv trusts the .envrc of a try when direnv is installed, so it
+// loads on the first cd.
+func AllowDirenv(path string) error {
+	if _, err := os.Stat(filepath.Join(path, ".envrc")); err != nil {
+		return nil
+	}
+	if _, err := exec.LookPath("direnv"); err != nil {
+		return nil
+	}
+	cmd := exec.Command("direnv", "allow", path)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func shellNix(pkgs []string) string {
+	return `{ pkgs ? import <nixpkgs> { } }:
+
+pkgs.mkShell {
+  packages = with pkgs; [ ` + nixList(pkgs) + `];
+}
+`
+}
+
+func flakeNix(pkgs []string) string {
+	return `{
+  inputs.nixpkgs.url = "github:NixOS/nixpkgs/nixos-unstable";
+  inputs.flake-utils.url = "github:numtide/flake-utils";
+
+  outputs = { nixpkgs, flake-utils, ... }:
+    flake-utils.lib.eachDefaultSystem (system:
+      let pkgs = nixpkgs.legacyPackages.${system}; in {
+        devShells.default = pkgs.mkShell {
+          packages = with pkgs; [ ` + nixList(pkgs) + `];
+        };
+      });
+}
+`
+}
+
+func nixList(pkgs []string) string {
+	if len(pkgs) == 0 {
+		return ""
+	}
+	return strings.Join(pkgs, " ") + " "
+}
+
+// toolVersions pins each plugin to its latest version when asdf can tell,
+// and to "latest" otherwise, which mise understands.
+func toolVersions(plugins []string) string {
+	var b strings.Builder
+	for _, plugin := range plugins {
+		version := "latest"
+		if out, err := exec.Command("asdf", "latest", plugin).Output(); err == nil {
+			if v := strings.TrimSpace(string(out)); v != "" {
+				version = v
+			}
+		}
+		fmt.Fprintf(&b, "%s %s\n", plugin, version)
+	}
+	return b.String()
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
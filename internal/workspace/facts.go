@@ -0,0 +1,26 @@
+package workspace
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// OldestModTime returns the earliest modification time of any file below
+// path, ignoring the .git directory.
+func OldestModTime(path string) (time.Time, error) {
+	var oldest time.Time
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info, err := d.Info(); err == nil && (oldest.IsZero() || info.ModTime().Before(oldest)) {
+			oldest = info.ModTime()
+		}
+		return nil
+	})
+	return oldest, err
+}
@@ -0,0 +1,152 @@
+package workspace
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// maxGrepFileSize bounds the files content search reads; anything larger
+// is almost certainly data rather than code.
+const maxGrepFileSize = 4 << 20
+
+// Hit is a line matching a content search.
+type Hit struct {
+	Dir  Directory
+	File string // absolute path
+	Rel  string // path within the try
+	Line int
+	Text string
+}
+
+// GrepOptions tunes a content search.
+type GrepOptions struct {
+	Skip    []string // folder name globs to leave out
+	Limit   int      // hits per try, unlimited when zero
+	Content map[string]*ContentIndex
+}
+
+// GrepTry searches the text files of a try for re, honouring .gitignore
+// files and skipping folders named in opts.Skip. It stops after opts.Limit
+// hits when that is positive. Files the root's content index rules out
+// are not read.
+func GrepTry(dir Directory, re *regexp.Regexp, opts GrepOptions) ([]Hit, error) {
+	content := opts.Content[dir.RootPath]
+	var grams []uint32
+	if content != nil {
+		grams = requiredTrigrams(re)
+	}
+
+	var hits []Hit
+	err := walkText(dir.Path, opts.Skip, func(abs, rel string, info os.FileInfo) bool {
+		if !content.mayMatch(dir, rel, info, grams) {
+			return true
+		}
+		found, err := grepFile(abs, re, opts.Limit-len(hits))
+		if err != nil {
+			return true
+		}
+		for _, h := range found {
+			h.Dir = dir
+			h.File = abs
+			h.Rel = filepath.FromSlash(rel)
+			hits = append(hits, h)
+		}
+		return opts.Limit <= 0 || len(hits) < opts.Limit
+	})
+	return hits, err
+}
+
+// grepFile returns the matching lines of a file, or nothing for binaries.
+func grepFile(file string, re *regexp.Regexp, limit int) ([]Hit, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err != nil || info.Size() > maxGrepFileSize {
+		return nil, err
+	}
+
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(f, head)
+	if isBinary(head[:n]) {
+		return nil, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxGrepFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		if re.Match(scanner.Bytes()) {
+			hits = append(hits, Hit{Line: line, Text: strings.TrimSpace(scanner.Text())})
+			if limit > 0 && len(hits) >= limit {
+				break
+			}
+		}
+	}
+	return hits, scanner.Err()
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Grep searches tries concurrently, taking up to opts.Limit hits from each, and
+// hands every try's hits to fn in the order of dirs. Searching stops early
+// once fn returns false.
+func Grep(dirs []Directory, re *regexp.Regexp, opts GrepOptions, fn func([]Hit) bool) {
+	results := make([]chan []Hit, len(dirs))
+	for i := range results {
+		results[i] = make(chan []Hit, 1)
+	}
+
+	jobs := make(chan int)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var hits []Hit
+				if d := dirs[i]; !d.Archived() {
+					hits, _ = GrepTry(d, re, opts)
+				}
+				results[i] <- hits
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range dirs {
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for i := range dirs {
+		if hits := <-results[i]; len(hits) > 0 && !fn(hits) {
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is one pattern from a .gitignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreFile holds the rules of a .gitignore and the directory they are
+// relative to, as a slash-separated path below the walk root.
+type ignoreFile struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// loadIgnore parses the .gitignore in dir, returning nil if there is none.
+// It covers the common syntax: comments, negation, trailing slashes for
+// directories, anchoring and ** wildcards.
+func loadIgnore(dir, rel string) *ignoreFile {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	ig := &ignoreFile{dir: rel}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+
+		pattern := globRegexp(line)
+		if !anchored {
+			pattern = `(^|.*/)` + pattern
+		}
+		re, err := regexp.Compile("^" + pattern + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		ig.rules = append(ig.rules, rule)
+	}
+	return ig
+}
+
+// globRegexp translates a gitignore glob into a regular expression.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString(`(.*/)?`)
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString(`(/.*)?`)
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(`.*`)
+			i++
+		case c == '*':
+			b.WriteString(`[^/]*`)
+		case c == '?':
+			b.WriteString(`[^/]`)
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignored reports whether the slash-separated path rel is excluded by the
+// stack of .gitignore files above it. Later, deeper rules win.
+func ignored(stack []*ignoreFile, rel string, isDir bool) bool {
+	result := false
+	for _, ig := range stack {
+		sub := rel
+		if ig.dir != "" {
+			if !strings.HasPrefix(rel, ig.dir+"/") {
+				continue
+			}
+			sub = rel[len(ig.dir)+1:]
+		}
+		for _, rule := range ig.rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(sub) {
+				result = !rule.negate
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,236 @@
+package workspace
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raiden076/gotry/internal/git"
+)
+
+const (
+	indexFile    = "index.gob"
+	indexVersion = 2
+)
+
+// index caches what listing a root costs to work out: the entries of each
+// directory level keyed by its modification time, with names already
+// parsed and the modification times of the tries in it, the decoded metadata keyed by the size and modification time of
+// meta.json, and the last known git state of each try. A nil index caches
+// nothing.
+type index struct {
+	Version     int
+	Naming      string
+	Levels      map[string]indexLevel
+	MetaModTime time.Time
+	MetaSize    int64
+	Meta        *Meta
+	Git         map[string]git.Status
+
+	changed bool
+	seen    map[string]bool
+}
+
+type indexLevel struct {
+	ModTime time.Time
+	Entries []indexEntry
+}
+
+type indexEntry struct {
+	Name    string
+	Nested  bool // may hold nested tries, see Naming.intermediate
+	Parsed  bool
+	Date    time.Time
+	Prefix  string
+	ModTime time.Time // of the entry, followed if it is a symlink
+}
+
+func indexPath(basePath string) string {
+	return filepath.Join(basePath, MetaDir, indexFile)
+}
+
+func namingKey(naming *Naming) string {
+	return naming.Try.String() + "\n" + naming.Clone.String()
+}
+
+// loadIndex reads the index of a root, starting afresh when it is missing,
+// unreadable or was built for other naming schemes.
+func loadIndex(basePath string, naming *Naming) *index {
+	fresh := &index{
+		Version: indexVersion,
+		Naming:  namingKey(naming),
+		Levels:  map[string]indexLevel{},
+		Git:     map[string]git.Status{},
+		changed: true,
+		seen:    map[string]bool{},
+	}
+
+	data, err := os.ReadFile(indexPath(basePath))
+	if err != nil {
+		return fresh
+	}
+	idx := &index{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(idx); err != nil ||
+		idx.Version != indexVersion || idx.Naming != fresh.Naming {
+		return fresh
+	}
+	if idx.Levels == nil {
+		idx.Levels = map[string]indexLevel{}
+	}
+	if idx.Git == nil {
+		idx.Git = map[string]git.Status{}
+	}
+	idx.seen = map[string]bool{}
+	return idx
+}
+
+func (idx *index) level(rel string, modTime time.Time) ([]indexEntry, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	key := filepath.ToSlash(rel)
+	idx.seen[key] = true
+	l, ok := idx.Levels[key]
+	if !ok || !l.ModTime.Equal(modTime) {
+		return nil, false
+	}
+	return l.Entries, true
+}
+
+func (idx *index) setLevel(rel string, modTime time.Time, entries []indexEntry) {
+	if idx == nil {
+		return
+	}
+	idx.Levels[filepath.ToSlash(rel)] = indexLevel{ModTime: modTime, Entries: entries}
+	idx.changed = true
+}
+
+// loadMeta returns the metadata index of the root, decoding meta.json only
+// when it changed.
+func (idx *index) loadMeta(basePath string) (*Meta, error) {
+	if idx == nil {
+		return LoadMeta(basePath)
+	}
+
+	info, err := os.Stat(metaPath(basePath))
+	if err != nil {
+		idx.MetaModTime, idx.MetaSize = time.Time{}, 0
+		return LoadMeta(basePath)
+	}
+	if idx.Meta != nil && info.ModTime().Equal(idx.MetaModTime) && info.Size() == idx.MetaSize {
+		return idx.Meta, nil
+	}
+
+	m, err := LoadMeta(basePath)
+	if err != nil {
+		return nil, err
+	}
+	idx.Meta, idx.MetaModTime, idx.MetaSize = m, info.ModTime(), info.Size()
+	idx.changed = true
+	return m, nil
+}
+
+func (idx *index) gitStatus(name string) (git.Status, bool) {
+	if idx == nil {
+		return git.Status{}, false
+	}
+	st, ok := idx.Git[filepath.ToSlash(name)]
+	return st, ok
+}
+
+// pruneGit forgets the git state of tries that are gone.
+func (idx *index) pruneGit(dirs []Directory) {
+	if idx == nil || len(idx.Git) == 0 {
+		return
+	}
+	present := make(map[string]bool, len(dirs))
+	for _, d := range dirs {
+		present[filepath.ToSlash(d.Name)] = true
+	}
+	for name := range idx.Git {
+		if !present[name] {
+			delete(idx.Git, name)
+			idx.changed = true
+		}
+	}
+}
+
+// save writes the index back if anything changed, forgetting levels that
+// were not visited. Failures only cost speed next time and are ignored.
+func (idx *index) save(basePath string) {
+	if idx == nil {
+		return
+	}
+	for key := range idx.Levels {
+		if !idx.seen[key] {
+			delete(idx.Levels, key)
+			idx.changed = true
+		}
+	}
+	if !idx.changed {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(idx); err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(basePath, MetaDir), 0755); err != nil {
+		return
+	}
+	writeAtomic(indexPath(basePath), buf.Bytes())
+}
+
+// SaveGitStatus records the git state of tries in their roots' indexes so
+// the next listing can show it straight away. Statuses are keyed by try
+// path.
+func SaveGitStatus(dirs []Directory, statuses map[string]git.Status) error {
+	byRoot := map[string][]Directory{}
+	for _, d := range dirs {
+		if _, ok := statuses[d.Path]; ok && !d.Graduated() {
+			byRoot[d.RootPath] = append(byRoot[d.RootPath], d)
+		}
+	}
+
+	for root, list := range byRoot {
+		lock, err := AcquireLock(root)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(indexPath(root))
+		idx := &index{}
+		if err != nil || gob.NewDecoder(bytes.NewReader(data)).Decode(idx) != nil || idx.Version != indexVersion {
+			lock.Release()
+			continue // rebuilt on the next listing
+		}
+		if idx.Git == nil {
+			idx.Git = map[string]git.Status{}
+		}
+		for _, d := range list {
+			idx.Git[filepath.ToSlash(d.Name)] = statuses[d.Path]
+		}
+		var buf bytes.Buffer
+		err = gob.NewEncoder(&buf).Encode(idx)
+		if err == nil {
+			err = writeAtomic(indexPath(root), buf.Bytes())
+		}
+		lock.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveIndex deletes the listing and content indexes of a root, so the
+// next listing scans it from scratch.
+func RemoveIndex(basePath string) error {
+	for _, p := range []string{indexPath(basePath), contentIndexPath(basePath)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// IsolateDir holds the home and XDG directories of an isolated try.
+const IsolateDir = ".isolated"
+
+const (
+	// IsolatedEnv names the isolated try the shell is in.
+	IsolatedEnv = "GOTRY_ISOLATED"
+	// RealHomeEnv keeps the user's own home while HOME points into a try.
+	RealHomeEnv = "GOTRY_REAL_HOME"
+)
+
+// EnvVar is an environment variable pointing into an isolated try.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// isolatedVars maps variables to the folder of IsolateDir they point to.
+var isolatedVars = []EnvVar{
+	{"HOME", "home"},
+	{"XDG_CONFIG_HOME", "config"},
+	{"XDG_CACHE_HOME", "cache"},
+	{"XDG_DATA_HOME", "data"},
+	{"XDG_STATE_HOME", "state"},
+}
+
+// windowsVars are the Windows counterparts, set alongside on Windows.
+var windowsVars = []EnvVar{
+	{"USERPROFILE", "home"},
+	{"APPDATA", "config"},
+	{"LOCALAPPDATA", "cache"},
+}
+
+// Isolate gives a try its own home and XDG directories. They are ignored
+// by git without touching the try's .gitignore.
+func Isolate(path string) error {
+	base := filepath.Join(path, IsolateDir)
+	for _, v := range isolatedVars {
+		if err := os.MkdirAll(filepath.Join(base, v.Value), 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(base, ".gitignore"), []byte("*\n"), 0644)
+}
+
+// IsolatedVars returns the variables to set inside an isolated try.
+func IsolatedVars(path string) []EnvVar {
+	vars := isolatedVars
+	if runtime.GOOS == "windows" {
+		vars = append(vars[:len(vars):len(vars)], windowsVars...)
+	}
+	out := make([]EnvVar, len(vars))
+	for i, v := range vars {
+		out[i] = EnvVar{v.Name, filepath.Join(path, IsolateDir, v.Value)}
+	}
+	return out
+}
+
+// FindIsolated returns the isolated try containing dir, if any.
+func FindIsolated(dir string) (string, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, IsolateDir, "home")); err == nil && info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,44 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// MetaDir is the hidden folder inside a workspace root that holds gotry's
+// own files. List never reports it as a try.
+const MetaDir = ".gotry"
+
+// Lock is a workspace-wide advisory lock. Writers of workspace metadata
+// hold it so concurrent gotry processes do not clobber each other.
+type Lock struct {
+	file *os.File
+}
+
+// AcquireLock blocks until the lock for basePath is held.
+func AcquireLock(basePath string) (*Lock, error) {
+	dir := filepath.Join(basePath, MetaDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &Lock{file: f}, nil
+}
+
+func (l *Lock) Release() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package workspace
+
+import "os"
+
+// Platforms without file locking fall back to no locking.
+
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,220 @@
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const metaFile = "meta.json"
+
+// Meta is the metadata index stored in each workspace root. Tries are keyed
+// by their slash-separated name relative to the root.
+type Meta struct {
+	Tries map[string]*TryMeta `json:"tries"`
+}
+
+type TryMeta struct {
+	Tags        []string  `json:"tags,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Origin      string    `json:"origin,omitempty"`       // one of the Origin constants
+	Parent      string    `json:"parent,omitempty"`       // path of the try a fork was made from
+	AdoptedFrom string    `json:"adopted_from,omitempty"` // original location of an adopted directory
+	Pinned      bool      `json:"pinned,omitempty"`
+	LastAccess  time.Time `json:"last_access,omitzero"`
+	Visits      int       `json:"visits,omitempty"`
+
+	// MovedTo is set on the tombstone left behind by a graduated try.
+	MovedTo string `json:"moved_to,omitempty"`
+
+	// Archive is the tarball an archived try was packed into.
+	Archive    string    `json:"archive,omitempty"`
+	ArchivedAt time.Time `json:"archived_at,omitzero"`
+
+	// CleanedAt is when build artifacts were last purged.
+	CleanedAt time.Time `json:"cleaned_at,omitzero"`
+
+	// Expires is set on temporary tries, which are also removed once the
+	// shell Session that made them exits.
+	Expires time.Time `json:"expires,omitzero"`
+	Session int       `json:"session,omitempty"`
+
+	// Isolated tries have their own home and XDG directories.
+	Isolated bool `json:"isolated,omitempty"`
+
+	// Env is the kind of environment the try was bootstrapped with.
+	Env string `json:"env,omitempty"`
+}
+
+const (
+	OriginNew   = "new"
+	OriginClone = "clone"
+	OriginFork  = "fork"
+	OriginAdopt = "adopt"
+)
+
+func metaPath(basePath string) string {
+	return filepath.Join(basePath, MetaDir, metaFile)
+}
+
+func metaKey(name string) string {
+	return filepath.ToSlash(name)
+}
+
+// LoadMeta reads the metadata index of a root. A missing index is empty.
+func LoadMeta(basePath string) (*Meta, error) {
+	m := &Meta{Tries: map[string]*TryMeta{}}
+
+	data, err := os.ReadFile(metaPath(basePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Tries == nil {
+		m.Tries = map[string]*TryMeta{}
+	}
+	return m, nil
+}
+
+// UpdateMeta applies fn to the metadata index of a root while holding the
+// workspace lock, then writes the result atomically.
+func UpdateMeta(basePath string, fn func(*Meta) error) error {
+	lock, err := AcquireLock(basePath)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	m, err := LoadMeta(basePath)
+	if err != nil {
+		return err
+	}
+	if err := fn(m); err != nil {
+		return err
+	}
+
+	// Drop entries that no longer carry anything
+	for key, t := range m.Tries {
+		if t.empty() {
+			delete(m.Tries, key)
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(metaPath(basePath), data)
+}
+
+// writeAtomic replaces a file by writing a temporary one beside it and
+// renaming it into place.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get returns the metadata of a try, or nil if it has none.
+func (m *Meta) Get(name string) *TryMeta {
+	return m.Tries[metaKey(name)]
+}
+
+// Entry returns the metadata of a try, creating it if needed.
+func (m *Meta) Entry(name string) *TryMeta {
+	key := metaKey(name)
+	t, ok := m.Tries[key]
+	if !ok {
+		t = &TryMeta{}
+		m.Tries[key] = t
+	}
+	return t
+}
+
+// Rename moves the metadata of a try to its new name.
+func (m *Meta) Rename(oldName, newName string) {
+	if t, ok := m.Tries[metaKey(oldName)]; ok {
+		delete(m.Tries, metaKey(oldName))
+		m.Tries[metaKey(newName)] = t
+	}
+}
+
+// Visit records an access to a try.
+func (t *TryMeta) Visit(at time.Time) {
+	t.LastAccess = at
+	t.Visits++
+}
+
+// Remove forgets a try.
+func (m *Meta) Remove(name string) {
+	delete(m.Tries, metaKey(name))
+}
+
+func (t *TryMeta) empty() bool {
+	return len(t.Tags) == 0 && t.Description == "" && t.Origin == "" && t.Parent == "" && t.AdoptedFrom == "" && !t.Pinned &&
+		t.LastAccess.IsZero() && t.Visits == 0 && t.MovedTo == "" && t.Archive == "" &&
+		t.CleanedAt.IsZero() && t.Expires.IsZero() && t.Session == 0 && !t.Isolated && t.Env == ""
+}
+
+// HasTag reports whether the try carries tag, ignoring case.
+func (t *TryMeta) HasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if strings.EqualFold(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TryMeta) AddTags(tags ...string) {
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !t.HasTag(tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	sort.Strings(t.Tags)
+}
+
+func (t *TryMeta) RemoveTags(tags ...string) {
+	kept := t.Tags[:0]
+	for _, have := range t.Tags {
+		drop := false
+		for _, tag := range tags {
+			if strings.EqualFold(have, NormalizeTag(tag)) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, have)
+		}
+	}
+	t.Tags = kept
+}
+
+// NormalizeTag strips a leading # and turns the rest into a slug.
+func NormalizeTag(tag string) string {
+	return Slugify(strings.TrimPrefix(strings.TrimSpace(tag), "#"), 0)
+}
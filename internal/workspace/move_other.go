@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package workspace
+
+import "os"
+
+func crossDevice(err error) bool { return false }
+
+func renameOver(src, dst string) error {
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
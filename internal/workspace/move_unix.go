@@ -0,0 +1,24 @@
+//go:build unix
+
+package workspace
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// crossDevice reports whether a rename failed because source and
+// destination are on different filesystems.
+func crossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// renameOver moves src onto dst, an empty directory, in one step.
+// os.Rename refuses to replace directories, rename(2) does not.
+func renameOver(src, dst string) error {
+	if err := syscall.Rename(src, dst); err != nil {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+	}
+	return nil
+}
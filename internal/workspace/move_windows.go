@@ -0,0 +1,23 @@
+//go:build windows
+
+package workspace
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func crossDevice(err error) bool {
+	return errors.Is(err, windows.ERROR_NOT_SAME_DEVICE)
+}
+
+// renameOver moves src onto dst, an empty directory. Windows does not
+// rename over directories, so dst is free for a moment.
+func renameOver(src, dst string) error {
+	if err := os.Remove(dst); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
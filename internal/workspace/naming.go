@@ -0,0 +1,369 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+const (
+	DefaultTryScheme   = `{{.Date "2006-01-02"}}-{{.Slug}}`
+	DefaultCloneScheme = `{{.Date "2006-01-02"}}-{{.User}}-{{.Repo}}`
+)
+
+// NameData is the value naming templates are executed against.
+type NameData struct {
+	Time time.Time
+	Slug string
+	Host string
+	User string
+	Repo string
+}
+
+func (d NameData) Date(layout string) string { return d.Time.Format(layout) }
+func (d NameData) Year() string              { return d.Time.Format("2006") }
+func (d NameData) Month() string             { return d.Time.Format("01") }
+func (d NameData) Day() string               { return d.Time.Format("02") }
+
+// Parsed is what a Scheme recovers from an existing directory name.
+type Parsed struct {
+	Date    time.Time
+	HasDate bool
+	Slug    string
+	Host    string
+	User    string
+	Repo    string
+
+	// Prefix is the leading part of the name made of date fields and
+	// separators, the rest being the human-readable name.
+	Prefix string
+}
+
+// Scheme renders directory names from a template and parses them back.
+// Slashes in the template produce nested directories.
+type Scheme struct {
+	src      string
+	tmpl     *template.Template
+	segments []segment
+}
+
+type segment struct {
+	re     *regexp.Regexp
+	fields []field
+}
+
+type field struct {
+	kind   string // date, year, month, day, slug, host, user, repo
+	layout string
+}
+
+// probe stands in for NameData while compiling a scheme, recording which
+// fields the template uses and where.
+type probe struct {
+	fields []field
+	Slug   string
+	Host   string
+	User   string
+	Repo   string
+}
+
+func (p *probe) mark(f field) string {
+	p.fields = append(p.fields, f)
+	return fmt.Sprintf("\x00%d\x00", len(p.fields)-1)
+}
+
+func (p *probe) Date(layout string) string { return p.mark(field{kind: "date", layout: layout}) }
+func (p *probe) Year() string              { return p.mark(field{kind: "year"}) }
+func (p *probe) Month() string             { return p.mark(field{kind: "month"}) }
+func (p *probe) Day() string               { return p.mark(field{kind: "day"}) }
+
+var sentinelRegex = regexp.MustCompile("\x00([0-9]+)\x00")
+
+func ParseScheme(src string) (*Scheme, error) {
+	tmpl, err := template.New("naming").Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming scheme %q: %w", src, err)
+	}
+
+	p := &probe{}
+	p.Slug = p.mark(field{kind: "slug"})
+	p.Host = p.mark(field{kind: "host"})
+	p.User = p.mark(field{kind: "user"})
+	p.Repo = p.mark(field{kind: "repo"})
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("invalid naming scheme %q: %w", src, err)
+	}
+
+	s := &Scheme{src: src, tmpl: tmpl}
+	for _, part := range strings.Split(buf.String(), "/") {
+		if part == "" || part == "." || part == ".." {
+			return nil, fmt.Errorf("invalid naming scheme %q: empty or relative path segment", src)
+		}
+
+		var seg segment
+		var pattern strings.Builder
+		pattern.WriteString("^")
+		last := 0
+		for _, loc := range sentinelRegex.FindAllStringSubmatchIndex(part, -1) {
+			pattern.WriteString(regexp.QuoteMeta(part[last:loc[0]]))
+			idx, _ := strconv.Atoi(part[loc[2]:loc[3]])
+			f := p.fields[idx]
+			pattern.WriteString("(" + fieldPattern(f) + ")")
+			seg.fields = append(seg.fields, f)
+			last = loc[1]
+		}
+		pattern.WriteString(regexp.QuoteMeta(part[last:]))
+		pattern.WriteString("$")
+
+		re, err := regexp.Compile(pattern.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid naming scheme %q: %w", src, err)
+		}
+		seg.re = re
+		s.segments = append(s.segments, seg)
+	}
+
+	return s, nil
+}
+
+func MustParseScheme(src string) *Scheme {
+	s, err := ParseScheme(src)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func fieldPattern(f field) string {
+	switch f.kind {
+	case "date":
+		return layoutPattern(f.layout)
+	case "year":
+		return `\d{4}`
+	case "month", "day":
+		return `\d{2}`
+	case "slug":
+		return `[^/]+`
+	default:
+		return `[^/]+?`
+	}
+}
+
+// layoutPattern turns a time layout into a regexp by formatting a sample
+// date and generalising its digit and letter runs.
+func layoutPattern(layout string) string {
+	sample := time.Date(2006, 11, 22, 15, 44, 55, 0, time.UTC).Format(layout)
+
+	var b strings.Builder
+	runes := []rune(sample)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsDigit(r):
+			for i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+				i++
+			}
+			b.WriteString(`\d+`)
+		case unicode.IsLetter(r):
+			for i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+				i++
+			}
+			b.WriteString(`[A-Za-z]+`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func (s *Scheme) String() string {
+	return s.src
+}
+
+// Depth is the number of nested directories a name spans.
+func (s *Scheme) Depth() int {
+	return len(s.segments)
+}
+
+// Render executes the scheme, returning an OS-specific relative path.
+func (s *Scheme) Render(d NameData) (string, error) {
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(buf.String()), nil
+}
+
+// Match parses a relative directory name produced by this scheme.
+func (s *Scheme) Match(rel string) (Parsed, bool) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) != len(s.segments) {
+		return Parsed{}, false
+	}
+
+	// Date fields can be spread over the name, so they are collected and
+	// parsed together
+	var p Parsed
+	var layouts, values []string
+	prefixDone := false
+	offset := 0
+
+	for i, part := range parts {
+		seg := s.segments[i]
+		loc := seg.re.FindStringSubmatchIndex(part)
+		if loc == nil {
+			return Parsed{}, false
+		}
+
+		for j, f := range seg.fields {
+			start, end := loc[2+2*j], loc[3+2*j]
+			value := part[start:end]
+
+			switch f.kind {
+			case "date":
+				layouts, values = append(layouts, f.layout), append(values, value)
+			case "year":
+				layouts, values = append(layouts, "2006"), append(values, value)
+			case "month":
+				layouts, values = append(layouts, "01"), append(values, value)
+			case "day":
+				layouts, values = append(layouts, "02"), append(values, value)
+			case "slug":
+				p.Slug = value
+			case "host":
+				p.Host = value
+			case "user":
+				p.User = value
+			case "repo":
+				p.Repo = value
+			}
+
+			if !prefixDone && !isDateField(f.kind) {
+				p.Prefix = filepath.ToSlash(rel)[:offset+start]
+				prefixDone = true
+			}
+		}
+		offset += len(part) + 1
+	}
+
+	if len(layouts) > 0 {
+		t, err := time.ParseInLocation(strings.Join(layouts, "\x00"), strings.Join(values, "\x00"), time.Local)
+		if err != nil {
+			return Parsed{}, false
+		}
+		p.Date, p.HasDate = t, true
+	}
+
+	if p.Slug == "" {
+		p.Slug = p.Repo
+	}
+
+	return p, true
+}
+
+func isDateField(kind string) bool {
+	return kind == "date" || kind == "year" || kind == "month" || kind == "day"
+}
+
+// Naming holds the schemes used for new tries and for clones.
+type Naming struct {
+	Try           *Scheme
+	Clone         *Scheme
+	UTC           bool
+	MaxSlugLength int
+}
+
+// DefaultNaming is the YYYY-MM-DD-name layout gotry has always used.
+var DefaultNaming = &Naming{
+	Try:           MustParseScheme(DefaultTryScheme),
+	Clone:         MustParseScheme(DefaultCloneScheme),
+	MaxSlugLength: DefaultMaxSlugLength,
+}
+
+func NewNaming(try, clone string, utc bool) (*Naming, error) {
+	if try == "" {
+		try = DefaultTryScheme
+	}
+	if clone == "" {
+		clone = DefaultCloneScheme
+	}
+
+	tryScheme, err := ParseScheme(try)
+	if err != nil {
+		return nil, err
+	}
+	cloneScheme, err := ParseScheme(clone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Naming{Try: tryScheme, Clone: cloneScheme, UTC: utc, MaxSlugLength: DefaultMaxSlugLength}, nil
+}
+
+func (n *Naming) now() time.Time {
+	if n.UTC {
+		return time.Now().UTC()
+	}
+	return time.Now()
+}
+
+// TryName renders the relative directory name for a new try.
+func (n *Naming) TryName(slug string) (string, error) {
+	return n.Try.Render(NameData{Time: n.now(), Slug: slug})
+}
+
+// TryNameAt renders the relative directory name for a try dated at.
+func (n *Naming) TryNameAt(slug string, at time.Time) (string, error) {
+	if n.UTC {
+		at = at.UTC()
+	}
+	return n.Try.Render(NameData{Time: at, Slug: slug})
+}
+
+// CloneName renders the relative directory name for a cloned repository.
+func (n *Naming) CloneName(host, user, repo string) (string, error) {
+	host, user, repo = cleanSegment(host), cleanSegment(user), cleanSegment(repo)
+	if user == "" || repo == "" {
+		return "", fmt.Errorf("invalid repository name %s/%s", user, repo)
+	}
+	return n.Clone.Render(NameData{
+		Time: n.now(),
+		Slug: Slugify(repo, n.MaxSlugLength),
+		Host: host,
+		User: user,
+		Repo: repo,
+	})
+}
+
+// Parse matches a relative name against the try scheme, then the clone
+// scheme.
+func (n *Naming) Parse(rel string) (Parsed, bool) {
+	if p, ok := n.Try.Match(rel); ok {
+		return p, true
+	}
+	return n.Clone.Match(rel)
+}
+
+// depth is the deepest nesting used by either scheme.
+func (n *Naming) depth() int {
+	return max(n.Try.Depth(), n.Clone.Depth())
+}
+
+// intermediate reports whether a directory at the given level could be one
+// of the parent folders of a nested name rather than a try itself.
+func (n *Naming) intermediate(level int, name string) bool {
+	for _, s := range []*Scheme{n.Try, n.Clone} {
+		if level < s.Depth()-1 && s.segments[level].re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
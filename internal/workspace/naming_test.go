@@ -0,0 +1,136 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchemeRoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 5, 14, 30, 0, 0, time.Local)
+	day := time.Date(2026, 3, 5, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		scheme string
+		slug   string
+		name   string
+		prefix string
+		date   time.Time // zero when the name carries no date
+	}{
+		{DefaultTryScheme, "api", "2026-03-05-api", "2026-03-05-", day},
+		{DefaultTryScheme, "api-server-v2", "2026-03-05-api-server-v2", "2026-03-05-", day},
+		{DefaultTryScheme, "2024-01-01-notes", "2026-03-05-2024-01-01-notes", "2026-03-05-", day},
+		{`{{.Year}}/{{.Month}}/{{.Slug}}`, "api-server", "2026/03/api-server", "2026/03/", day.AddDate(0, 0, -4)},
+		{`{{.Year}}/{{.Month}}-{{.Day}}/{{.Slug}}`, "api", "2026/03-05/api", "2026/03-05/", day},
+		{`{{.Date "2006-01"}}/{{.Date "02"}}_{{.Slug}}`, "api-server", "2026-03/05_api-server", "2026-03/05_", day},
+		{`{{.Date "20060102"}}_{{.Slug}}`, "api_server_2", "20260305_api_server_2", "20260305_", day},
+		{`{{.Date "Jan 02 2006"}} {{.Slug}}`, "api", "Mar 05 2026 api", "Mar 05 2026 ", day},
+		{`{{.Slug}}-{{.Date "2006-01-02"}}`, "api-server", "api-server-2026-03-05", "", day},
+		{`tries/{{.Slug}}`, "api", "tries/api", "tries/", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := ParseScheme(tt.scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &Naming{Try: scheme, Clone: DefaultNaming.Clone}
+			name, err := n.TryNameAt(tt.slug, at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.name {
+				t.Fatalf("rendered %q, want %q", name, tt.name)
+			}
+
+			p, ok := scheme.Match(name)
+			if !ok {
+				t.Fatalf("%q does not match its own scheme", name)
+			}
+			if p.Slug != tt.slug {
+				t.Errorf("slug %q, want %q", p.Slug, tt.slug)
+			}
+			if p.Prefix != tt.prefix {
+				t.Errorf("prefix %q, want %q", p.Prefix, tt.prefix)
+			}
+			if p.HasDate != !tt.date.IsZero() || !p.Date.Equal(tt.date) {
+				t.Errorf("date %v (%v), want %v", p.Date, p.HasDate, tt.date)
+			}
+		})
+	}
+}
+
+func TestCloneSchemeRoundTrip(t *testing.T) {
+	tests := []struct {
+		scheme           string
+		host, user, repo string
+		name             string
+	}{
+		{DefaultCloneScheme, "github.com", "user", "repo", "-user-repo"},
+		{`{{.Host}}/{{.User}}/{{.Repo}}`, "github.com", "my-org", "repo-name", "github.com/my-org/repo-name"},
+		{`{{.User}}/{{.Repo}}-{{.Date "2006-01-02"}}`, "gitlab.com", "user", "my-repo", "user/my-repo-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			scheme, err := ParseScheme(tt.scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := &Naming{Try: DefaultNaming.Try, Clone: scheme}
+			name, err := n.CloneName(tt.host, tt.user, tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(name, tt.name) {
+				t.Fatalf("rendered %q, want it to contain %q", name, tt.name)
+			}
+
+			p, ok := scheme.Match(name)
+			if !ok {
+				t.Fatalf("%q does not match its own scheme", name)
+			}
+			if p.User != tt.user || p.Repo != tt.repo || p.Slug != tt.repo {
+				t.Errorf("parsed user %q, repo %q, slug %q", p.User, p.Repo, p.Slug)
+			}
+		})
+	}
+}
+
+func TestSchemeMismatch(t *testing.T) {
+	tests := []struct {
+		scheme string
+		name   string
+	}{
+		{DefaultTryScheme, "notes"},
+		{DefaultTryScheme, "2026-13-45-api"},
+		{DefaultTryScheme, "2026-03-05"},
+		{`{{.Year}}/{{.Month}}/{{.Slug}}`, "2026/api"},
+		{`{{.Year}}/{{.Month}}/{{.Slug}}`, "2026/3/api"},
+		{`{{.Year}}/{{.Month}}/{{.Slug}}`, "2026/03/api/extra"},
+		{`{{.Year}}/{{.Month}}/{{.Slug}}`, "2026/13/api"},
+		{`{{.Date "20060102"}}_{{.Slug}}`, "2026-03-05-api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p, ok := MustParseScheme(tt.scheme).Match(tt.name); ok {
+				t.Errorf("%q matches %s as %+v", tt.name, tt.scheme, p)
+			}
+		})
+	}
+}
+
+func TestParseSchemeErrors(t *testing.T) {
+	for _, src := range []string{
+		`{{.Slug`,
+		`{{.Nope}}`,
+		`{{.Year}}//{{.Slug}}`,
+		`../{{.Slug}}`,
+		`{{.Slug}}/`,
+	} {
+		if _, err := ParseScheme(src); err == nil {
+			t.Errorf("ParseScheme(%q) accepted it", src)
+		}
+	}
+}
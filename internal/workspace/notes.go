@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NotesFile is kept in the try itself, so notes travel with it when it is
+// graduated, archived or forked.
+const NotesFile = "NOTES.md"
+
+// noteLayout is the timestamp heading that starts each entry.
+const noteLayout = "2006-01-02 15:04"
+
+// Note is one timestamped entry of a try's notes.
+type Note struct {
+	Time time.Time
+	Text string
+}
+
+// NotesPath returns the notes file of a try.
+func NotesPath(d Directory) string {
+	return filepath.Join(d.Path, NotesFile)
+}
+
+// StartNote appends a timestamp heading for a new entry, creating the
+// notes file when needed, and returns the file's path. Text, if any, is
+// written as the entry.
+func StartNote(d Directory, text string, at time.Time) (string, error) {
+	if d.Archived() {
+		return "", fmt.Errorf("%s is archived, restore it first", d.Name)
+	}
+	path := NotesPath(d)
+
+	var b strings.Builder
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		fmt.Fprintf(&b, "# %s\n", d.Name)
+	case err != nil:
+		return "", err
+	case info.Size() > 0:
+		// Entries are separated by a blank line whatever the file ended with
+		if data, err := os.ReadFile(path); err == nil && !strings.HasSuffix(string(data), "\n") {
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "\n## %s\n\n", at.Format(noteLayout))
+	if text = strings.TrimSpace(text); text != "" {
+		b.WriteString(text + "\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// ReadNotes returns the entries of a try's notes file, oldest first. Text
+// before the first timestamp heading is not an entry, and entries left
+// empty are skipped. A missing file has no notes.
+func ReadNotes(d Directory) ([]Note, error) {
+	f, err := os.Open(NotesPath(d))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var notes []Note
+	var cur *Note
+	var text []string
+	flush := func() {
+		if cur != nil {
+			cur.Text = strings.TrimSpace(strings.Join(text, "\n"))
+			if cur.Text != "" {
+				notes = append(notes, *cur)
+			}
+		}
+		text = nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			if at, err := time.ParseInLocation(noteLayout, strings.TrimSpace(heading), time.Local); err == nil {
+				flush()
+				cur = &Note{Time: at}
+				continue
+			}
+		}
+		text = append(text, line)
+	}
+	flush()
+	return notes, scanner.Err()
+}
+
+// LatestNote returns the most recent entry of a try's notes.
+func LatestNote(d Directory) (Note, bool) {
+	notes, err := ReadNotes(d)
+	if err != nil || len(notes) == 0 {
+		return Note{}, false
+	}
+	return notes[len(notes)-1], true
+}
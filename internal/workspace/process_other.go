@@ -0,0 +1,9 @@
+//go:build !unix
+
+package workspace
+
+// processAlive cannot tell here, so temporary tries only expire with
+// their TTL.
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package workspace
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process exists, even if it belongs to
+// another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
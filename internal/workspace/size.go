@@ -0,0 +1,188 @@
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const sizeCacheFile = "sizes.json"
+
+// SizeCache remembers, for every directory below a root, its modification
+// time, the bytes held by its files and its subdirectories. Directories
+// whose modification time is unchanged are not read again, so rescanning an
+// idle workspace costs one stat per directory. A file rewritten in place is
+// only noticed once something else touches its directory.
+type SizeCache struct {
+	mu      sync.Mutex
+	base    string
+	dirs    map[string]cachedDir
+	seen    map[string]bool
+	changed bool
+}
+
+type cachedDir struct {
+	ModTime time.Time `json:"mtime"`
+	Bytes   int64     `json:"bytes"`
+	Subdirs []string  `json:"subdirs,omitempty"`
+}
+
+// LoadSizeCache reads the size cache of a root. A missing or unreadable
+// cache is empty.
+func LoadSizeCache(basePath string) *SizeCache {
+	c := &SizeCache{base: basePath, dirs: map[string]cachedDir{}, seen: map[string]bool{}}
+	if data, err := os.ReadFile(filepath.Join(basePath, MetaDir, sizeCacheFile)); err == nil {
+		json.Unmarshal(data, &c.dirs)
+	}
+	return c
+}
+
+// Size returns the bytes held by the regular files below path.
+func (c *SizeCache) Size(path string) (int64, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	return c.size(path, info.ModTime()), nil
+}
+
+func (c *SizeCache) size(path string, modTime time.Time) int64 {
+	key := c.key(path)
+
+	c.mu.Lock()
+	cached, ok := c.dirs[key]
+	c.seen[key] = true
+	c.mu.Unlock()
+
+	if !ok || !cached.ModTime.Equal(modTime) {
+		cached = cachedDir{ModTime: modTime}
+		entries, _ := os.ReadDir(path)
+		for _, e := range entries {
+			switch {
+			case e.IsDir():
+				cached.Subdirs = append(cached.Subdirs, e.Name())
+			case e.Type().IsRegular():
+				if info, err := e.Info(); err == nil {
+					cached.Bytes += info.Size()
+				}
+			}
+		}
+
+		c.mu.Lock()
+		c.dirs[key] = cached
+		c.changed = true
+		c.mu.Unlock()
+	}
+
+	total := cached.Bytes
+	for _, sub := range cached.Subdirs {
+		p := filepath.Join(path, sub)
+		info, err := os.Lstat(p)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		total += c.size(p, info.ModTime())
+	}
+	return total
+}
+
+func (c *SizeCache) key(path string) string {
+	if rel, err := filepath.Rel(c.base, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// Save writes the cache back, dropping directories that were not visited
+// and no longer exist.
+func (c *SizeCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.dirs {
+		if c.seen[key] {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(c.base, filepath.FromSlash(key))); os.IsNotExist(err) {
+			delete(c.dirs, key)
+			c.changed = true
+		}
+	}
+	if !c.changed {
+		return nil
+	}
+
+	data, err := json.Marshal(c.dirs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(c.base, MetaDir), 0755); err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(c.base, MetaDir, sizeCacheFile), data)
+}
+
+// ScanSizes measures tries concurrently using each root's size cache,
+// returning sizes by path. Archived tries count as the size of their
+// archive; graduated ones are left out.
+func ScanSizes(dirs []Directory) map[string]int64 {
+	caches := map[string]*SizeCache{}
+	for _, d := range dirs {
+		if caches[d.RootPath] == nil {
+			caches[d.RootPath] = LoadSizeCache(d.RootPath)
+		}
+	}
+
+	var mu sync.Mutex
+	sizes := make(map[string]int64, len(dirs))
+	jobs := make(chan Directory)
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				var n int64
+				var err error
+				switch {
+				case d.Graduated():
+					continue
+				case d.Archived():
+					var info os.FileInfo
+					if info, err = os.Stat(d.Meta.Archive); err == nil {
+						n = info.Size()
+					}
+				default:
+					n, err = caches[d.RootPath].Size(d.Path)
+				}
+				if err == nil {
+					mu.Lock()
+					sizes[d.Path] = n
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, d := range dirs {
+		jobs <- d
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, c := range caches {
+		c.Save()
+	}
+	return sizes
+}
+
+// SortBySize orders tries largest first, keeping the existing order among
+// equal sizes.
+func SortBySize(dirs []Directory, sizes map[string]int64) {
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return sizes[dirs[i].Path] > sizes[dirs[j].Path]
+	})
+}
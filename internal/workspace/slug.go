@@ -0,0 +1,86 @@
+package workspace
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxSlugLength caps slugs when no limit is configured.
+const DefaultMaxSlugLength = 64
+
+// transliterations covers letters that do not decompose into an ASCII base
+// letter plus combining marks.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d",
+	'ð': "d", 'þ': "th", 'ı': "i", 'ħ': "h", 'ŋ': "ng", 'ſ': "s",
+}
+
+// Slugify turns free text into a single safe path segment: accents are
+// stripped, separators, punctuation and control characters collapse into
+// single hyphens, and the result is cut to maxLen runes.
+func Slugify(name string, maxLen int) string {
+	if maxLen <= 0 {
+		maxLen = DefaultMaxSlugLength
+	}
+
+	var b strings.Builder
+	pendingSep, afterWord := false, false
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if t, ok := transliterations[r]; ok {
+			writeSep(&b, pendingSep)
+			pendingSep, afterWord = false, true
+			b.WriteString(t)
+			continue
+		}
+
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			writeSep(&b, pendingSep)
+			pendingSep, afterWord = false, true
+			b.WriteRune(r)
+		case (r == '.' || r == '_') && afterWord:
+			// Keep single dots and underscores inside words (v1.2, foo_bar)
+			b.WriteRune(r)
+			afterWord = false
+		case r == '.' || r == '_':
+			continue
+		default:
+			pendingSep, afterWord = true, false
+		}
+	}
+
+	runes := []rune(b.String())
+	if len(runes) > maxLen {
+		runes = runes[:maxLen]
+	}
+	return strings.TrimRight(string(runes), "-._")
+}
+
+// writeSep emits a pending hyphen, replacing a dangling dot or underscore.
+func writeSep(b *strings.Builder, pending bool) {
+	if !pending || b.Len() == 0 {
+		return
+	}
+	s := strings.TrimRight(b.String(), "._")
+	b.Reset()
+	b.WriteString(s)
+	b.WriteByte('-')
+}
+
+// cleanSegment makes a clone URL component safe to use as a path segment
+// while keeping its case.
+func cleanSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '-'
+		}
+		return r
+	}, s)
+	return strings.Trim(s, ".")
+}
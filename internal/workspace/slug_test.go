@@ -0,0 +1,49 @@
+package workspace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"Redis Experiment", 0, "redis-experiment"},
+		{"  spaces   and\ttabs ", 0, "spaces-and-tabs"},
+		{"../x", 0, "x"},
+		{"../../etc/passwd", 0, "etc-passwd"},
+		{"foo/bar", 0, "foo-bar"},
+		{`foo\bar`, 0, "foo-bar"},
+		{".hidden", 0, "hidden"},
+		{"..", 0, ""},
+		{"v1.2 release", 0, "v1.2-release"},
+		{"foo_bar baz", 0, "foo_bar-baz"},
+		{"trailing.", 0, "trailing"},
+		{"a._-b", 0, "a-b"},
+		{"Über Café", 0, "uber-cafe"},
+		{"Straße Øresund Łódź", 0, "strasse-oresund-lodz"},
+		{"ﬁle ①", 0, "file-1"},
+		{"日本語 test", 0, "日本語-test"},
+		{"line\nbreak\x00nul", 0, "line-break-nul"},
+		{"!@#$%^&*()", 0, ""},
+		{"", 0, ""},
+		{"___...", 0, ""},
+		{strings.Repeat("a", 300), 0, strings.Repeat("a", DefaultMaxSlugLength)},
+		{strings.Repeat("abc ", 100), 0, strings.Repeat("abc-", 15) + "abc"},
+		{strings.Repeat("é", 300), 10, "eeeeeeeeee"},
+		{"abc def", 4, "abc"},
+	}
+
+	for _, tt := range tests {
+		got := Slugify(tt.in, tt.max)
+		if got != tt.want {
+			t.Errorf("Slugify(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+		if strings.ContainsAny(got, `/\`) || got == "." || got == ".." {
+			t.Errorf("Slugify(%q) = %q is not a single path segment", tt.in, got)
+		}
+	}
+}
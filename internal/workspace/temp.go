@@ -0,0 +1,26 @@
+package workspace
+
+import "time"
+
+// Temporary reports whether the try lives in the temporary root and will
+// be removed once it expires.
+func (d Directory) Temporary() bool {
+	return !d.Meta.Expires.IsZero()
+}
+
+// Expired reports whether a temporary try has outlived its TTL or the
+// shell session that made it.
+func (d Directory) Expired(now time.Time) bool {
+	if !d.Temporary() {
+		return false
+	}
+	if now.After(d.Meta.Expires) {
+		return true
+	}
+	return d.Meta.Session > 0 && !processAlive(d.Meta.Session)
+}
+
+// TimeLeft formats the time until a temporary try expires, as in 3h.
+func (d Directory) TimeLeft() string {
+	return RelativeTime(time.Now().Add(-time.Until(d.Meta.Expires)))
+}
@@ -0,0 +1,325 @@
+package workspace
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sync"
+	"time"
+)
+
+const contentIndexFile = "trigrams.gob"
+
+// ContentIndex records, for every text file of the tries in a root, the
+// trigrams it contains, so content searches only read files that can
+// match. Letters are folded to lower case, which keeps it usable for
+// case-insensitive searches. Entries are keyed by size and modification
+// time; files that changed since they were indexed are simply read.
+type ContentIndex struct {
+	mu      sync.Mutex
+	base    string
+	tries   map[string]map[string]contentFile // try name -> slash path -> file
+	changed bool
+}
+
+type contentFile struct {
+	ModTime time.Time
+	Size    int64
+	Binary  bool
+	Grams   []uint32 // sorted
+}
+
+func contentIndexPath(basePath string) string {
+	return filepath.Join(basePath, MetaDir, contentIndexFile)
+}
+
+// LoadContentIndex reads the content index of a root, or returns nil when
+// the root has none.
+func LoadContentIndex(basePath string) *ContentIndex {
+	data, err := os.ReadFile(contentIndexPath(basePath))
+	if err != nil {
+		return nil
+	}
+	c := &ContentIndex{base: basePath}
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(&c.tries) != nil {
+		return nil
+	}
+	return c
+}
+
+// NewContentIndex starts an empty content index for a root.
+func NewContentIndex(basePath string) *ContentIndex {
+	return &ContentIndex{base: basePath, tries: map[string]map[string]contentFile{}, changed: true}
+}
+
+// LoadContentIndexes loads the content index of every root of dirs that
+// has one, keyed by root path.
+func LoadContentIndexes(dirs []Directory) map[string]*ContentIndex {
+	indexes := map[string]*ContentIndex{}
+	for _, d := range dirs {
+		if _, ok := indexes[d.RootPath]; ok {
+			continue
+		}
+		indexes[d.RootPath] = LoadContentIndex(d.RootPath)
+	}
+	for root, c := range indexes {
+		if c == nil {
+			delete(indexes, root)
+		}
+	}
+	return indexes
+}
+
+// Update brings the entries of a try up to date, reading only the files
+// whose size or modification time changed, and forgets files that are
+// gone. It returns the number of files read.
+func (c *ContentIndex) Update(dir Directory, skip []string) (int, error) {
+	c.mu.Lock()
+	old := c.tries[dir.Name]
+	c.mu.Unlock()
+
+	files := make(map[string]contentFile, len(old))
+	read := 0
+	err := walkText(dir.Path, skip, func(abs, rel string, info os.FileInfo) bool {
+		if f, ok := old[rel]; ok && f.Size == info.Size() && f.ModTime.Equal(info.ModTime()) {
+			files[rel] = f
+			return true
+		}
+		f := contentFile{ModTime: info.ModTime(), Size: info.Size()}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return true
+		}
+		read++
+		if isBinary(data) {
+			f.Binary = true
+		} else {
+			f.Grams = trigramsOf(data)
+		}
+		files[rel] = f
+		return true
+	})
+
+	c.mu.Lock()
+	c.tries[dir.Name] = files
+	c.changed = true
+	c.mu.Unlock()
+	return read, err
+}
+
+// Prune drops the entries of tries not in keep.
+func (c *ContentIndex) Prune(keep []Directory) {
+	names := map[string]bool{}
+	for _, d := range keep {
+		names[d.Name] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.tries {
+		if !names[name] {
+			delete(c.tries, name)
+			c.changed = true
+		}
+	}
+}
+
+// Save writes the index if it changed.
+func (c *ContentIndex) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.changed {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c.tries); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(c.base, MetaDir), 0755); err != nil {
+		return err
+	}
+	if err := writeAtomic(contentIndexPath(c.base), buf.Bytes()); err != nil {
+		return err
+	}
+	c.changed = false
+	return nil
+}
+
+// Files returns the number of files indexed.
+func (c *ContentIndex) Files() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, files := range c.tries {
+		n += len(files)
+	}
+	return n
+}
+
+// mayMatch reports whether a file can hold a match for a pattern that
+// requires grams. Files the index does not know about, or that changed
+// since they were indexed, may always match.
+func (c *ContentIndex) mayMatch(dir Directory, rel string, info os.FileInfo, grams []uint32) bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	f, ok := c.tries[dir.Name][rel]
+	c.mu.Unlock()
+	if !ok || f.Size != info.Size() || !f.ModTime.Equal(info.ModTime()) {
+		return true
+	}
+	if f.Binary {
+		return false
+	}
+	for _, g := range grams {
+		if _, found := slices.BinarySearch(f.Grams, g); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// walkText calls fn for each regular file of a try that content search
+// looks at, until fn returns false: .git, metadata, folders matching skip,
+// ignored paths and files over maxGrepFileSize are left out.
+func walkText(root string, skip []string, fn func(abs, rel string, info os.FileInfo) bool) error {
+	var walk func(abs, rel string, stack []*ignoreFile) bool
+	walk = func(abs, rel string, stack []*ignoreFile) bool {
+		if ig := loadIgnore(abs, rel); ig != nil {
+			stack = append(stack[:len(stack):len(stack)], ig)
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			return true
+		}
+		for _, e := range entries {
+			name := e.Name()
+			childRel := path.Join(rel, name)
+			childAbs := filepath.Join(abs, name)
+			if e.IsDir() {
+				if name == ".git" || name == MetaDir || matchAny(skip, name) || ignored(stack, childRel, true) {
+					continue
+				}
+				if !walk(childAbs, childRel, stack) {
+					return false
+				}
+				continue
+			}
+			if !e.Type().IsRegular() || ignored(stack, childRel, false) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || info.Size() > maxGrepFileSize {
+				continue
+			}
+			if !fn(childAbs, childRel, info) {
+				return false
+			}
+		}
+		return true
+	}
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	walk(root, "", nil)
+	return nil
+}
+
+// isBinary treats data with a NUL byte near the start as binary.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+func gram(a, b, c byte) uint32 {
+	return uint32(foldByte(a))<<16 | uint32(foldByte(b))<<8 | uint32(foldByte(c))
+}
+
+func foldByte(b byte) byte {
+	if 'A' <= b && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
+
+// trigramsOf returns the distinct trigrams of data, sorted.
+func trigramsOf(data []byte) []uint32 {
+	seen := map[uint32]struct{}{}
+	for i := 0; i+3 <= len(data); i++ {
+		seen[gram(data[i], data[i+1], data[i+2])] = struct{}{}
+	}
+	grams := make([]uint32, 0, len(seen))
+	for g := range seen {
+		grams = append(grams, g)
+	}
+	slices.Sort(grams)
+	return grams
+}
+
+// requiredTrigrams returns trigrams every match of re must contain. An
+// empty result means the index cannot narrow the search.
+func requiredTrigrams(re *regexp.Regexp) []uint32 {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	var grams []uint32
+	for _, lit := range requiredLiterals(parsed.Simplify()) {
+		for i := 0; i+3 <= len(lit); i++ {
+			grams = append(grams, gram(lit[i], lit[i+1], lit[i+2]))
+		}
+	}
+	slices.Sort(grams)
+	return slices.Compact(grams)
+}
+
+// requiredLiterals collects runs of literal text that appear in every
+// match. Alternations and optional parts contribute nothing.
+func requiredLiterals(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if foldsNonASCII(re) {
+			return nil
+		}
+		return []string{string(re.Rune)}
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiterals(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		// Adjacent literals join into one run, so "ab" + "cd" yields "abcd"
+		var out []string
+		run := ""
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral && !foldsNonASCII(sub) {
+				run += string(sub.Rune)
+				continue
+			}
+			if run != "" {
+				out = append(out, run)
+				run = ""
+			}
+			out = append(out, requiredLiterals(sub)...)
+		}
+		if run != "" {
+			out = append(out, run)
+		}
+		return out
+	}
+	return nil
+}
+
+// foldsNonASCII reports a case-insensitive literal outside ASCII, whose
+// other cases the index does not fold.
+func foldsNonASCII(re *syntax.Regexp) bool {
+	if re.Flags&syntax.FoldCase == 0 {
+		return false
+	}
+	return slices.ContainsFunc(re.Rune, func(r rune) bool { return r >= 0x80 })
+}
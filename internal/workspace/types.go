@@ -0,0 +1,51 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ProjectType is a kind of project recognised by its marker files.
+type ProjectType struct {
+	Name    string
+	Markers []string
+	Icon    string
+	ASCII   string // three-column stand-in for terminals without emoji
+}
+
+// ProjectTypes lists the known types, most specific first; a try showing
+// several is represented by the earliest.
+var ProjectTypes = []ProjectType{
+	{Name: "go", Markers: []string{"go.mod"}, Icon: "🐹", ASCII: "go "},
+	{Name: "rust", Markers: []string{"Cargo.toml"}, Icon: "🦀", ASCII: "rs "},
+	{Name: "node", Markers: []string{"package.json"}, Icon: "🟩", ASCII: "js "},
+	{Name: "python", Markers: []string{"pyproject.toml", "setup.py", "requirements.txt"}, Icon: "🐍", ASCII: "py "},
+	{Name: "ruby", Markers: []string{"Gemfile"}, Icon: "💎", ASCII: "rb "},
+	{Name: "nix", Markers: []string{"flake.nix"}, Icon: "🧊", ASCII: "nix"},
+	{Name: "docker", Markers: []string{"Dockerfile"}, Icon: "🐳", ASCII: "dkr"},
+}
+
+// LookupType returns the project type with the given name.
+func LookupType(name string) (ProjectType, bool) {
+	for _, t := range ProjectTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return ProjectType{}, false
+}
+
+// DetectTypes returns the names of the project types whose marker files
+// are present at the top level of path, in ProjectTypes order.
+func DetectTypes(path string) []string {
+	var types []string
+	for _, t := range ProjectTypes {
+		for _, marker := range t.Markers {
+			if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+				types = append(types, t.Name)
+				break
+			}
+		}
+	}
+	return types
+}
@@ -2,97 +2,402 @@ package workspace
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/raiden076/gotry/internal/git"
 )
 
 type Directory struct {
-	Name      string
-	Path      string
-	ModTime   time.Time
-	DatePart  string
-	NamePart  string
+	Name     string
+	Path     string
+	Root     string
+	RootPath string
+	ModTime  time.Time
+	Date     time.Time // zero when the name carries no date
+	DatePart string    // leading date portion of Name, separators included
+	NamePart string
+	Meta     TryMeta
+	Git      *git.Status // last known git state, from the index
+}
+
+// Graduated reports whether the entry is the tombstone of a try that was
+// moved out of the workspace.
+func (d Directory) Graduated() bool {
+	return d.Meta.MovedTo != ""
+}
+
+// LastUsed is the later of the last modification and the last visit
+// recorded in the access history.
+func (d Directory) LastUsed() time.Time {
+	if d.Meta.LastAccess.After(d.ModTime) {
+		return d.Meta.LastAccess
+	}
+	return d.ModTime
+}
+
+// Archived reports whether the try is packed away in an archive; its Path
+// is where it will be restored to.
+func (d Directory) Archived() bool {
+	return d.Meta.Archive != ""
+}
+
+// Discard deletes a try and forgets its metadata. Graduated tries live
+// outside the workspace, so only their tombstone goes; archived tries lose
+// their archive.
+func Discard(d Directory) error {
+	switch {
+	case d.Graduated():
+	case d.Archived():
+		if err := os.Remove(d.Meta.Archive); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	default:
+		if err := os.RemoveAll(d.Path); err != nil {
+			return err
+		}
+		pruneEmptyParents(d.RootPath, filepath.Dir(d.Path))
+	}
+
+	return UpdateMeta(d.RootPath, func(m *Meta) error {
+		m.Remove(d.Name)
+		return nil
+	})
+}
+
+// Root is a labeled directory holding tries.
+type Root struct {
+	Name string
+	Path string
+}
+
+// ListRoots lists every root and merges the results, most recently used
+// first. Roots are read through their on-disk index, so only directories
+// that changed since the last call are scanned again.
+func ListRoots(roots []Root, naming *Naming) ([]Directory, error) {
+	return listRoots(roots, naming, false)
+}
+
+// RefreshIndex lists every root like ListRoots, but reads every directory
+// level again, picking up changes inside tries that the cached levels do
+// not show.
+func RefreshIndex(roots []Root, naming *Naming) ([]Directory, error) {
+	return listRoots(roots, naming, true)
 }
 
-func List(basePath string) ([]Directory, error) {
-	entries, err := os.ReadDir(basePath)
+func listRoots(roots []Root, naming *Naming, refresh bool) ([]Directory, error) {
+	if len(roots) == 1 {
+		dirs, err := listRoot(roots[0], naming, refresh)
+		return dirs, err // already in order
+	}
+
+	var all []Directory
+	for _, root := range roots {
+		dirs, err := listRoot(root, naming, refresh)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, dirs...)
+	}
+
+	sortByLastUsed(all)
+	return all, nil
+}
+
+// sortByLastUsed orders tries most recently used first, keeping ties in
+// place. Directories are large, so the order is worked out on their times
+// and the entries moved once.
+func sortByLastUsed(dirs []Directory) {
+	used := make([]time.Time, len(dirs))
+	order := make([]int, len(dirs))
+	for i := range dirs {
+		used[i] = dirs[i].LastUsed()
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return used[b].Compare(used[a])
+	})
+	sorted := make([]Directory, len(dirs))
+	for i, j := range order {
+		sorted[i] = dirs[j]
+	}
+	copy(dirs, sorted)
+}
+
+func listRoot(root Root, naming *Naming, refresh bool) ([]Directory, error) {
+	idx := loadIndex(root.Path, naming)
+	if refresh {
+		clear(idx.Levels)
+	}
+	dirs, err := list(root.Path, naming, idx)
 	if err != nil {
+		return nil, err
+	}
+	idx.save(root.Path)
+	for i := range dirs {
+		dirs[i].Root = root.Name
+	}
+	return dirs, nil
+}
+
+// List scans the tries below basePath without using or writing an index,
+// for directories that are not (yet) workspace roots.
+func List(basePath string, naming *Naming) ([]Directory, error) {
+	return list(basePath, naming, nil)
+}
+
+func list(basePath string, naming *Naming, idx *index) ([]Directory, error) {
+	dirs := []Directory{}
+	if err := idx.listLevel(basePath, "", 0, naming, &dirs); err != nil {
 		if os.IsNotExist(err) {
 			return []Directory{}, nil
 		}
 		return nil, err
 	}
 
-	var dirs []Directory
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	meta, err := idx.loadMeta(basePath)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dirs {
+		dirs[i].RootPath = basePath
+		if t := meta.Get(dirs[i].Name); t != nil {
+			dirs[i].Meta = *t
 		}
-
-		info, err := entry.Info()
-		if err != nil {
-			continue
+		if st, ok := idx.gitStatus(dirs[i].Name); ok {
+			dirs[i].Git = &st
 		}
+	}
 
-		name := entry.Name()
-		datePart, namePart := parseDirectoryName(name)
+	idx.pruneGit(dirs)
 
-		dirs = append(dirs, Directory{
+	// Graduated and archived tries stay findable
+	for key, t := range meta.Tries {
+		name := filepath.FromSlash(key)
+		dir := Directory{
 			Name:     name,
 			Path:     filepath.Join(basePath, name),
-			ModTime:  info.ModTime(),
-			DatePart: datePart,
-			NamePart: namePart,
-		})
+			RootPath: basePath,
+			NamePart: name,
+			Meta:     *t,
+		}
+
+		switch {
+		case t.MovedTo != "":
+			info, err := os.Stat(t.MovedTo)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			dir.Path = t.MovedTo
+			dir.ModTime = info.ModTime()
+		case t.Archive != "":
+			if _, err := os.Stat(dir.Path); err == nil {
+				continue // restored by hand
+			}
+			dir.ModTime = t.ArchivedAt
+		default:
+			continue
+		}
+
+		if parsed, ok := naming.Parse(name); ok {
+			dir.Date = parsed.Date
+			dir.DatePart = filepath.FromSlash(parsed.Prefix)
+			dir.NamePart = name[len(dir.DatePart):]
+		}
+		dirs = append(dirs, dir)
 	}
 
-	// Sort by modification time, most recent first
-	sort.Slice(dirs, func(i, j int) bool {
-		return dirs[i].ModTime.After(dirs[j].ModTime)
-	})
+	sortByLastUsed(dirs)
 
 	return dirs, nil
 }
 
-func parseDirectoryName(name string) (datePart, namePart string) {
-	// Expected format: YYYY-MM-DD-name
-	if len(name) >= 11 && name[4] == '-' && name[7] == '-' && name[10] == '-' {
-		return name[:10], name[11:]
+// listLevel collects tries below basePath/rel, descending into the parent
+// folders of nested naming schemes. A level whose modification time matches
+// the index is not read again, and its tries keep the modification times
+// recorded when it was: adding or removing a try changes the level, while
+// changes inside a try are only seen once the level is read again (see
+// RefreshIndex).
+func (idx *index) listLevel(basePath, rel string, level int, naming *Naming, dirs *[]Directory) error {
+	levelPath := filepath.Join(basePath, rel)
+	info, err := os.Stat(levelPath)
+	if err != nil {
+		return err
+	}
+
+	entries, cached := idx.level(rel, info.ModTime())
+	if !cached {
+		if entries, err = scanLevel(basePath, rel, level, naming); err != nil {
+			return err
+		}
+		idx.setLevel(rel, info.ModTime(), entries)
+	}
+
+	*dirs = slices.Grow(*dirs, len(entries))
+	for _, e := range entries {
+		name := filepath.Join(rel, e.Name)
+		path := filepath.Join(basePath, name)
+
+		if e.Nested && !isRepo(path) {
+			var nested []Directory
+			if err := idx.listLevel(basePath, name, level+1, naming, &nested); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			// A folder with nothing matching below it is a try of its own
+			if len(nested) > 0 {
+				*dirs = append(*dirs, nested...)
+				continue
+			}
+		}
+
+		dir := Directory{
+			Name:     name,
+			Path:     path,
+			ModTime:  e.ModTime,
+			NamePart: name,
+		}
+		if e.Parsed {
+			dir.Date = e.Date
+			dir.DatePart = filepath.FromSlash(e.Prefix)
+			dir.NamePart = name[len(dir.DatePart):]
+		}
+
+		*dirs = append(*dirs, dir)
+	}
+
+	return nil
+}
+
+// statAll stats the entries of a directory, spreading large levels over a
+// few goroutines. Entries that cannot be stat'ed are nil.
+func statAll(dir string, entries []indexEntry) []os.FileInfo {
+	infos := make([]os.FileInfo, len(entries))
+	workers := min(8, len(entries)/256+1)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(entries); i += workers {
+				if info, err := os.Stat(dir + string(filepath.Separator) + entries[i].Name); err == nil {
+					infos[i] = info
+				}
+			}
+		}()
 	}
-	return "", name
+	wg.Wait()
+	return infos
 }
 
-func Create(basePath, name string) (string, error) {
-	today := time.Now().Format("2006-01-02")
-	dirName := fmt.Sprintf("%s-%s", today, sanitizeName(name))
-	fullPath := filepath.Join(basePath, dirName)
+// scanLevel reads one directory level, classifying each entry as a try or
+// as a possible parent folder of nested tries. Symlinked tries (see adopt)
+// are followed; entries that are not directories are left out.
+func scanLevel(basePath, rel string, level int, naming *Naming) ([]indexEntry, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(basePath, rel))
+	if err != nil {
+		return nil, err
+	}
 
-	// Handle collisions
-	finalPath := fullPath
-	counter := 2
-	for {
-		if _, err := os.Stat(finalPath); os.IsNotExist(err) {
-			break
+	var entries []indexEntry
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !entry.IsDir() && entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		name := filepath.Join(rel, entry.Name())
+		e := indexEntry{Name: entry.Name()}
+		if parsed, ok := naming.Parse(name); ok {
+			e.Parsed = true
+			e.Date = parsed.Date
+			e.Prefix = parsed.Prefix
+		} else if naming.intermediate(level, entry.Name()) {
+			e.Nested = true
+		}
+		entries = append(entries, e)
+	}
+
+	infos := statAll(filepath.Join(basePath, rel), entries)
+	kept := entries[:0]
+	for i, e := range entries {
+		if infos[i] == nil || !infos[i].IsDir() {
+			continue
 		}
-		finalPath = fmt.Sprintf("%s-%d", fullPath, counter)
-		counter++
+		e.ModTime = infos[i].ModTime()
+		kept = append(kept, e)
+	}
+	return kept, nil
+}
+
+func Create(basePath, name string, naming *Naming) (string, error) {
+	slug := Slugify(name, naming.MaxSlugLength)
+	if slug == "" {
+		return "", fmt.Errorf("name %q has no usable characters", name)
 	}
 
-	if err := os.MkdirAll(finalPath, 0755); err != nil {
+	dirName, err := naming.TryName(slug)
+	if err != nil {
 		return "", err
 	}
+	fullPath := filepath.Join(basePath, dirName)
+	if !Within(basePath, fullPath) {
+		return "", fmt.Errorf("name %q resolves outside %s", name, basePath)
+	}
 
-	return finalPath, nil
+	return Reserve(fullPath)
 }
 
-func sanitizeName(name string) string {
-	// Replace spaces with hyphens, lowercase
-	name = strings.ToLower(name)
-	name = strings.ReplaceAll(name, " ", "-")
-	return name
+// Reserve atomically creates path, or path-2, path-3, ... if taken, and
+// returns the directory it created. Concurrent callers never receive the
+// same directory.
+func Reserve(path string) (string, error) {
+	return reserve(path, func(candidate string) error {
+		return os.Mkdir(candidate, 0755)
+	})
+}
+
+// ReserveLink creates a symlink to target at path, or path-2, path-3, ...
+// if taken, picking names the way Reserve does.
+func ReserveLink(target, path string) (string, error) {
+	return reserve(path, func(candidate string) error {
+		return os.Symlink(target, candidate)
+	})
+}
+
+// reserve calls create on path and its numbered variants until one is not
+// taken. create must fail with an os.ErrExist error for a taken name.
+func reserve(path string, create func(string) error) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	candidate := path
+	for counter := 2; ; counter++ {
+		err := create(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%d", path, counter)
+	}
+}
+
+// Within reports whether path is basePath or lies below it.
+func Within(basePath, path string) bool {
+	rel, err := filepath.Rel(basePath, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
 }
 
 func Delete(paths []string) error {
@@ -124,3 +429,207 @@ func RelativeTime(t time.Time) string {
 		return fmt.Sprintf("%dw", weeks)
 	}
 }
+
+// HumanSize formats a byte count with a binary unit, as in 1.5G.
+func HumanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// CopyTree copies the contents of src into the existing directory dst.
+func CopyTree(src, dst string) error {
+	return CopyTreeFiltered(src, dst, nil)
+}
+
+// CopyTreeFiltered is CopyTree with a filter: entries for which skip
+// returns true are left out, along with everything below them. Files
+// already in dst are overwritten.
+func CopyTreeFiltered(src, dst string, skip func(rel string, d os.DirEntry) bool) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skip != nil && skip(rel, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Rename gives a try a new slug, keeping the date portion of its name, and
+// carries its metadata over. It returns the new name relative to the root.
+func Rename(dir Directory, newName string, naming *Naming) (string, error) {
+	if dir.Graduated() {
+		return "", fmt.Errorf("%s has graduated to %s", dir.Name, dir.Path)
+	}
+	if dir.Archived() {
+		return "", fmt.Errorf("%s is archived, restore it first", dir.Name)
+	}
+
+	slug := Slugify(newName, naming.MaxSlugLength)
+	if slug == "" {
+		return "", fmt.Errorf("name %q has no usable characters", newName)
+	}
+
+	name := dir.DatePart + slug
+	if !Within(dir.RootPath, filepath.Join(dir.RootPath, name)) {
+		return "", fmt.Errorf("name %q resolves outside %s", newName, dir.RootPath)
+	}
+	if name == dir.Name {
+		return name, nil
+	}
+
+	if err := Move(dir.RootPath, dir.Name, name); err != nil {
+		return "", err
+	}
+
+	err := UpdateMeta(dir.RootPath, func(m *Meta) error {
+		m.Rename(dir.Name, name)
+		return nil
+	})
+	return name, err
+}
+
+// MoveDir moves a directory anywhere, copying it when src and dst are on
+// different filesystems.
+func MoveDir(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	}
+	if Within(src, dst) {
+		return fmt.Errorf("cannot move %s into itself", src)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	// Only a move across filesystems is worth a copy; any other failure,
+	// like dst lying inside src, would go wrong the same way copying
+	err := os.Rename(src, dst)
+	if err == nil || !crossDevice(err) {
+		return err
+	}
+
+	if err := os.Mkdir(dst, 0755); err != nil {
+		return err
+	}
+	if err := CopyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// MoveInto moves a directory onto dst, an empty directory made by Reserve,
+// so the name stays taken throughout. It copies when src and dst are on
+// different filesystems, and removes dst if the move fails.
+func MoveInto(src, dst string) error {
+	if Within(src, dst) {
+		return fmt.Errorf("cannot move %s into itself", src)
+	}
+
+	err := renameOver(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !crossDevice(err) {
+		os.Remove(dst)
+		return err
+	}
+
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	if err := CopyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// Move renames a try within basePath, creating the parent folders a nested
+// name needs and removing the ones the old name leaves empty.
+func Move(basePath, oldName, newName string) error {
+	oldPath := filepath.Join(basePath, oldName)
+	newPath := filepath.Join(basePath, newName)
+
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("%s already exists", newPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	pruneEmptyParents(basePath, filepath.Dir(oldPath))
+	return nil
+}
+
+func pruneEmptyParents(basePath, dir string) {
+	for dir != basePath && strings.HasPrefix(dir, basePath) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func isRepo(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil
+}
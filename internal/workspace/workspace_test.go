@@ -0,0 +1,251 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const stressWorkers = 32
+
+// createAndRecord is what creating or cloning a try does to a root: reserve
+// a directory, then record it in meta.json.
+func createAndRecord(root string, clone bool, tag string) (string, error) {
+	var path string
+	var err error
+	if clone {
+		path, err = Reserve(filepath.Join(root, "github.com", "user", "repo"))
+	} else {
+		path, err = Create(root, "same name", DefaultNaming)
+	}
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", err
+	}
+	return rel, UpdateMeta(root, func(m *Meta) error {
+		m.Entry(rel).AddTags(tag)
+		return nil
+	})
+}
+
+func checkStress(t *testing.T, root string, names []string) {
+	t.Helper()
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			t.Errorf("%s handed out twice", name)
+		}
+		seen[name] = true
+		if info, err := os.Stat(filepath.Join(root, name)); err != nil || !info.IsDir() {
+			t.Errorf("%s was not created", name)
+		}
+	}
+
+	m, err := LoadMeta(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range seen {
+		if e := m.Get(name); e == nil || len(e.Tags) != 1 {
+			t.Errorf("meta.json lost the entry of %s", name)
+		}
+	}
+	if len(m.Tries) != len(seen) {
+		t.Errorf("meta.json has %d entries, want %d", len(m.Tries), len(seen))
+	}
+}
+
+func TestConcurrentCreate(t *testing.T) {
+	root := t.TempDir()
+
+	var mu sync.Mutex
+	var names []string
+	var wg sync.WaitGroup
+	for i := range stressWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name, err := createAndRecord(root, i%2 == 1, fmt.Sprintf("w%d", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			names = append(names, name)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(names) != stressWorkers {
+		t.Fatalf("created %d tries, want %d", len(names), stressWorkers)
+	}
+	checkStress(t, root, names)
+}
+
+// TestConcurrentCreateProcesses runs the workers as separate processes, as
+// several shells running gotry at once would.
+func TestConcurrentCreateProcesses(t *testing.T) {
+	if os.Getenv("GOTRY_STRESS_ROOT") != "" {
+		t.Skip("helper process")
+	}
+	root := t.TempDir()
+
+	cmds := make([]*exec.Cmd, stressWorkers)
+	outs := make([][]byte, stressWorkers)
+	var wg sync.WaitGroup
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestStressHelper$")
+		cmds[i].Env = append(os.Environ(),
+			"GOTRY_STRESS_ROOT="+root,
+			fmt.Sprintf("GOTRY_STRESS_WORKER=%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := cmds[i].Output()
+			if err != nil {
+				t.Errorf("worker %d: %v", i, err)
+			}
+			outs[i] = out
+		}()
+	}
+	wg.Wait()
+
+	var names []string
+	for _, out := range outs {
+		var name string
+		if _, err := fmt.Sscanf(string(out), "created %s\n", &name); err == nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) != stressWorkers {
+		t.Fatalf("created %d tries, want %d", len(names), stressWorkers)
+	}
+	checkStress(t, root, names)
+}
+
+// TestStressHelper is one worker of TestConcurrentCreateProcesses.
+func TestStressHelper(t *testing.T) {
+	root := os.Getenv("GOTRY_STRESS_ROOT")
+	if root == "" {
+		t.Skip("run by TestConcurrentCreateProcesses")
+	}
+	var worker int
+	fmt.Sscan(os.Getenv("GOTRY_STRESS_WORKER"), &worker)
+
+	// Line the workers up so they race
+	time.Sleep(time.Until(time.Now().Truncate(100 * time.Millisecond).Add(100 * time.Millisecond)))
+
+	name, err := createAndRecord(root, worker%2 == 1, fmt.Sprintf("w%d", worker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Printf("created %s\n", filepath.ToSlash(name))
+}
+
+func TestMoveIntoReservation(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dest, err := Reserve(filepath.Join(root, "try"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveInto(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "try", "sub")); err != nil {
+		t.Errorf("moved directory is missing: %v", err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("source still exists: %v", err)
+	}
+
+	// The name is taken, by the move above and then by the link
+	for _, want := range []string{"try-2", "try-3"} {
+		link, err := ReserveLink(src, filepath.Join(root, "try"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link != filepath.Join(root, want) {
+			t.Errorf("linked at %s, want %s", link, want)
+		}
+	}
+}
+
+func TestCleanDue(t *testing.T) {
+	root := t.TempDir()
+	if !CleanDue(root, time.Hour) {
+		t.Fatal("never cleaned, but not due")
+	}
+	if CleanDue(root, time.Hour) {
+		t.Error("due again straight after")
+	}
+
+	stamp := filepath.Join(root, MetaDir, cleanStampFile)
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(stamp, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if !CleanDue(root, time.Hour) {
+		t.Error("not due after the interval")
+	}
+}
+
+func TestIndexTimes(t *testing.T) {
+	root := Root{Name: "t", Path: t.TempDir()}
+	old := time.Date(2020, 6, 1, 12, 0, 0, 0, time.Local)
+	for _, name := range []string{"2020-06-01-api", "2020-06-01-web"} {
+		path := filepath.Join(root.Path, name)
+		if err := os.Mkdir(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTimes := func(list func([]Root, *Naming) ([]Directory, error)) map[string]time.Time {
+		t.Helper()
+		dirs, err := list([]Root{root}, DefaultNaming)
+		if err != nil {
+			t.Fatal(err)
+		}
+		times := map[string]time.Time{}
+		for _, d := range dirs {
+			times[d.Name] = d.ModTime
+		}
+		return times
+	}
+	modTimes(ListRoots) // creates .gotry, changing the root
+	modTimes(ListRoots)
+
+	// A change inside a try leaves its level alone
+	if err := os.WriteFile(filepath.Join(root.Path, "2020-06-01-api", "main.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := modTimes(ListRoots)["2020-06-01-api"]; !got.Equal(old) {
+		t.Errorf("cached listing saw the try change: %v", got)
+	}
+	if got := modTimes(RefreshIndex)["2020-06-01-api"]; got.Equal(old) {
+		t.Error("refreshed listing missed the try change")
+	}
+
+	// Adding a try changes the level, which is read again
+	if err := os.Mkdir(filepath.Join(root.Path, "2020-06-02-cli"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	times := modTimes(ListRoots)
+	if len(times) != 3 || !times["2020-06-01-web"].Equal(old) {
+		t.Errorf("after adding a try, listed %v", times)
+	}
+}